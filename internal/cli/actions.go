@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+// reportToActions integrates with GitHub Actions when running inside a
+// workflow: it appends the Markdown summary to the job summary and emits an
+// annotation for every repo that failed or couldn't be audited.
+func reportToActions(configPath string, run auditRun) error {
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open job summary: %w", err)
+		}
+		defer f.Close()
+		if err := writeMarkdown(f, configPath, run); err != nil {
+			return err
+		}
+	}
+
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		writeAnnotations(statusOut, run.Results)
+	}
+	return nil
+}
+
+// writeAnnotations emits ::error commands for non-compliant repos and
+// ::warning commands for repos that errored
+func writeAnnotations(w io.Writer, results []RepoAuditResult) {
+	for _, r := range results {
+		switch r.Status {
+		case StatusNonCompliant:
+			var rules []string
+			for _, d := range config.Failing(r.Diffs) {
+				rules = append(rules, fmt.Sprintf("%s [%s]: want %s, got %s", d.Rule, d.Severity, d.Want, d.Got))
+			}
+			fmt.Fprintf(w, "::error title=%s::%s\n",
+				escapeProperty(r.FullName()+" is non-compliant"),
+				escapeData(strings.Join(rules, "\n")))
+		case StatusError:
+			fmt.Fprintf(w, "::warning title=%s::%s\n",
+				escapeProperty(r.FullName()+" could not be audited"),
+				escapeData(r.Error))
+		}
+	}
+}
+
+// escapeData escapes a workflow command message
+func escapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeProperty escapes a workflow command property value
+func escapeProperty(s string) string {
+	s = escapeData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
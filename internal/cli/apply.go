@@ -2,89 +2,210 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/wdm0006/rampart/internal/config"
 	"github.com/wdm0006/rampart/internal/github"
 )
 
+// Apply outcomes reported for each repo that needed an update
+const (
+	OutcomeUpdated           = "updated"
+	OutcomeStillNonCompliant = "still_non_compliant"
+	OutcomeFailed            = "failed"
+	OutcomeWouldUpdate       = "would_update"
+)
+
+// ApplyResult holds the apply outcome for a single repo. Diffs lists the
+// rules that were (or would be) changed, or for still_non_compliant the
+// rules that didn't stick.
+type ApplyResult struct {
+	Owner   string            `json:"owner"`
+	Repo    string            `json:"repo"`
+	Branch  string            `json:"branch"`
+	Outcome string            `json:"outcome"`
+	Diffs   []config.RuleDiff `json:"diffs,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
 var applyCmd = &cobra.Command{
 	Use:   "apply",
 	Short: "Apply branch protection rules to non-compliant repos",
 	Long:  `Applies the branch protection rules defined in rampart.yaml to any repos that don't match the desired configuration.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		owner, _ := cmd.Flags().GetString("owner")
+		owners, _ := cmd.Flags().GetStringSlice("owner")
+		includeForks, _ := cmd.Flags().GetBool("include-forks")
 		repo, _ := cmd.Flags().GetString("repo")
+		reposFile, _ := cmd.Flags().GetString("repos-file")
 		exclude, _ := cmd.Flags().GetStringSlice("exclude")
 		configPath, _ := cmd.Flags().GetString("config")
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		format, _ := cmd.Flags().GetString("format")
 
-		if owner == "" {
-			user, err := github.GetCurrentUser()
-			if err != nil {
-				exitWithError(err.Error())
-			}
-			owner = user
-		}
+		setOutputFormat(format, formatText, formatJSON)
 
-		results, cfg := auditRepos(owner, repo, configPath, exclude)
+		run := auditRepos(auditOptions{
+			Owners:       owners,
+			Repo:         repo,
+			ConfigPath:   configPath,
+			Exclude:      exclude,
+			IncludeForks: includeForks,
+			ReposFile:    reposFile,
+		})
+		results := run.Results
+		multiOwner := len(run.Owners) > 1
 
 		// Find non-compliant repos
 		var toUpdate []RepoAuditResult
 		for _, r := range results {
-			if !r.Compliant && !r.Skipped && r.Error == "" {
+			if r.Status == StatusNonCompliant {
 				toUpdate = append(toUpdate, r)
 			}
 		}
 
+		counts := summarize(results)
+		summary := applySummary{Skipped: counts.NotChecked()}
+		applied := []ApplyResult{}
+
 		if len(toUpdate) == 0 {
-			fmt.Println("\nAll repos are compliant. Nothing to apply.")
-			return
+			fmt.Fprintln(statusOut, "\nAll repos are compliant. Nothing to apply.")
+		} else {
+			fmt.Fprintf(statusOut, "\n%d repo(s) to update:\n\n", len(toUpdate))
 		}
 
-		fmt.Printf("\n%d repo(s) to update:\n\n", len(toUpdate))
-
-		updated := 0
-		failed := 0
 		for _, r := range toUpdate {
+			res := ApplyResult{Owner: r.Owner, Repo: r.Repo, Branch: r.Branch}
+			name := repoLabel(r, multiOwner)
+
 			if dryRun {
-				fmt.Printf("  [dry-run] %s would be updated:\n", r.Repo)
-				for _, d := range r.Diffs {
-					if !d.Pass {
-						fmt.Printf("      %s: %s → %s\n", d.Rule, d.Got, d.Want)
-					}
+				fmt.Fprintf(statusOut, "  [dry-run] %s would be updated:\n", name)
+				res.Outcome = OutcomeWouldUpdate
+				res.Diffs = config.Failing(r.Diffs)
+				for _, d := range res.Diffs {
+					fmt.Fprintf(statusOut, "      %s: %s → %s\n", d.Rule, d.Got, d.Want)
 				}
-			} else {
-				fmt.Printf("  Updating %s...", r.Repo)
-				err := github.SetBranchProtection(owner, r.Repo, r.Branch, cfg.Rules)
-				if err != nil {
-					fmt.Printf(" failed: %s\n", err)
-					failed++
-				} else {
-					fmt.Println(" done")
-					updated++
+				summary.WouldUpdate++
+				applied = append(applied, res)
+				continue
+			}
+
+			fmt.Fprintf(statusOut, "  Updating %s...", name)
+			res = applyRepo(r)
+			switch res.Outcome {
+			case OutcomeUpdated:
+				fmt.Fprintln(statusOut, " done")
+				summary.Updated++
+			case OutcomeStillNonCompliant:
+				fmt.Fprintln(statusOut, " applied but still non-compliant")
+				for _, d := range res.Diffs {
+					fmt.Fprintf(statusOut, "      %s: want %s, got %s\n", d.Rule, d.Want, d.Got)
 				}
+				summary.StillNonCompliant++
+			default:
+				fmt.Fprintf(statusOut, " %s\n", res.Error)
+				summary.Failed++
 			}
+			applied = append(applied, res)
 		}
 
-		fmt.Println()
-		if dryRun {
-			fmt.Printf("Dry run complete: %d repo(s) would be updated\n", len(toUpdate))
-		} else {
-			skipped := 0
-			for _, r := range results {
-				if r.Skipped {
-					skipped++
-				}
+		if len(toUpdate) > 0 {
+			fmt.Fprintln(statusOut)
+			if dryRun {
+				fmt.Fprintf(statusOut, "Dry run complete: %d repo(s) would be updated\n", summary.WouldUpdate)
+			} else {
+				fmt.Fprintf(statusOut, "Results: %d updated, %d still non-compliant, %d failed, %d skipped\n",
+					summary.Updated, summary.StillNonCompliant, summary.Failed, summary.Skipped)
 			}
-			fmt.Printf("Results: %d updated, %d failed, %d skipped\n", updated, failed, skipped)
+		}
+
+		if format == formatJSON {
+			out := applyJSON{
+				SchemaVersion: jsonSchemaVersion,
+				Owner:         run.OwnerLabel(),
+				Config:        configPath,
+				Branch:        run.Config.Branch,
+				GeneratedAt:   nowRFC3339(),
+				DryRun:        dryRun,
+				Summary:       summary,
+				Results:       applied,
+			}
+			if err := writeJSON(os.Stdout, out); err != nil {
+				exitWithError(err.Error())
+			}
+		}
+
+		if summary.Failed > 0 {
+			os.Exit(exitApplyFailed)
+		}
+		if summary.StillNonCompliant > 0 {
+			os.Exit(exitStillNonCompliant)
 		}
 	},
 }
 
 func init() {
-	applyCmd.Flags().String("owner", "", "GitHub user or org to apply rules to (defaults to authenticated user)")
+	applyCmd.Flags().StringSlice("owner", nil, "GitHub users or orgs to apply rules to, comma-separated (defaults to owners in the config, then the authenticated user)")
 	applyCmd.Flags().String("repo", "", "Apply to a single repo instead of all repos")
-	applyCmd.Flags().StringSlice("exclude", nil, "Repos to exclude (repeatable)")
+	applyCmd.Flags().String("repos-file", "", "Apply to exactly the owner/name repos listed in this file, one per line or as a JSON array (- for stdin)")
+	applyCmd.Flags().Bool("include-forks", false, "Apply to forks too (also select.include_forks in the config)")
+	applyCmd.Flags().StringSlice("exclude", nil, "Repos to exclude, as name or owner/name (repeatable)")
 	applyCmd.Flags().String("config", "rampart.yaml", "Path to config file")
 	applyCmd.Flags().Bool("dry-run", false, "Preview changes without applying")
+	applyCmd.Flags().String("format", formatText, "Output format: text or json")
+}
+
+// Exit codes for apply. A failed update takes precedence over one that was
+// applied but didn't stick.
+const (
+	exitApplyFailed       = 1
+	exitStillNonCompliant = 2
+)
+
+// applyRepo pushes the repo's desired rules to a non-compliant repo and
+// verifies that GitHub actually stored them
+func applyRepo(r RepoAuditResult) ApplyResult {
+	res := ApplyResult{Owner: r.Owner, Repo: r.Repo, Branch: r.Branch, Diffs: config.Failing(r.Diffs)}
+	if err := github.SetBranchProtection(r.Owner, r.Repo, r.Branch, r.Desired); err != nil {
+		res.Outcome = OutcomeFailed
+		res.Error = fmt.Sprintf("failed: %s", err)
+		return res
+	}
+
+	// GitHub can accept the PUT but silently drop settings the plan
+	// doesn't support, so re-read and compare what was stored.
+	remaining, err := verifyProtection(r)
+	if err != nil {
+		res.Outcome = OutcomeFailed
+		res.Error = fmt.Sprintf("applied, but verification failed: %s", err)
+		return res
+	}
+	if len(remaining) > 0 {
+		res.Outcome = OutcomeStillNonCompliant
+		res.Diffs = remaining
+		return res
+	}
+
+	res.Outcome = OutcomeUpdated
+	return res
+}
+
+// verifyProtection re-fetches a branch's protection after an update and
+// returns the rules that still don't match the desired config, with the
+// severity and source the audit reported for them
+func verifyProtection(r RepoAuditResult) ([]config.RuleDiff, error) {
+	actual, _, err := github.GetBranchProtection(r.Owner, r.Repo, r.Branch)
+	if err != nil {
+		return nil, err
+	}
+	audited := make(map[string]config.RuleDiff, len(r.Diffs))
+	for _, d := range r.Diffs {
+		audited[d.Rule] = d
+	}
+	remaining := config.Failing(config.Compare(r.Desired, actual))
+	for i := range remaining {
+		remaining[i].Severity = audited[remaining[i].Rule].Severity
+		remaining[i].Source = audited[remaining[i].Rule].Source
+	}
+	return remaining, nil
 }
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+// stubGH puts a gh on PATH that accepts any update and reports the given
+// branch protection
+func stubGH(t *testing.T, protection string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub gh is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *--method*) exit 0 ;; esac\ncat <<'EOF'\n" + protection + "\nEOF\n"
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestApplyRepoStillNonCompliantKeepsSeverity(t *testing.T) {
+	// GitHub stores the update but drops enforce_admins
+	stubGH(t, `{"required_pull_request_reviews":{"required_approving_review_count":1,"dismiss_stale_reviews":true},"enforce_admins":{"enabled":false}}`)
+
+	cfg := config.Default()
+	r := RepoAuditResult{
+		Owner:   "acme",
+		Repo:    "api",
+		Branch:  "main",
+		Status:  StatusNonCompliant,
+		Desired: cfg.Rules,
+		Diffs: []config.RuleDiff{
+			{Rule: "enforce_admins", Pass: false, Want: "true", Got: "false", Severity: cfg.Severity("enforce_admins"), Source: config.SourceCentral},
+			{Rule: "required_approvals", Pass: true, Want: "1", Got: "1", Severity: cfg.Severity("required_approvals")},
+		},
+	}
+
+	res := applyRepo(r)
+	if res.Outcome != OutcomeStillNonCompliant {
+		t.Fatalf("outcome = %s (%s), want %s", res.Outcome, res.Error, OutcomeStillNonCompliant)
+	}
+	if len(res.Diffs) != 1 || res.Diffs[0].Rule != "enforce_admins" {
+		t.Fatalf("diffs = %+v, want only enforce_admins", res.Diffs)
+	}
+	if d := res.Diffs[0]; d.Severity != cfg.Severity("enforce_admins") || d.Source != config.SourceCentral {
+		t.Errorf("diff severity %q, source %q; want %q, %q", d.Severity, d.Source, cfg.Severity("enforce_admins"), config.SourceCentral)
+	}
+}
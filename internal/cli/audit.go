@@ -1,188 +1,716 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/wdm0006/rampart/internal/config"
 	"github.com/wdm0006/rampart/internal/github"
+	"github.com/wdm0006/rampart/internal/history"
+)
+
+// Audit statuses reported for each repo
+const (
+	StatusCompliant    = "compliant"
+	StatusNonCompliant = "non_compliant"
+	StatusError        = "error"
+	StatusSkipped      = "skipped"
+	// StatusArchived repos are read-only, so they're listed but not checked
+	StatusArchived = "archived"
+	// StatusNoBranch repos don't have the branch to check, e.g. empty repos
+	StatusNoBranch = "no_branch"
+	// StatusInactive repos fall outside select.pushed_within or
+	// select.created_after, so they're listed but not checked
+	StatusInactive = "inactive"
 )
 
 // RepoAuditResult holds the audit result for a single repo
 type RepoAuditResult struct {
-	Repo      string
-	Branch    string
-	Compliant bool
-	Diffs     []config.RuleDiff
-	Error     string
-	Skipped   bool
+	Owner      string            `json:"owner"`
+	Repo       string            `json:"repo"`
+	Branch     string            `json:"branch,omitempty"`
+	Status     string            `json:"status"`
+	Diffs      []config.RuleDiff `json:"diffs,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	SkipReason string            `json:"skip_reason,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	// Policy names the policy the repo was matched to; empty for the base rules
+	Policy string `json:"policy,omitempty"`
+	// Score is the weighted compliance score (0–100); nil for repos that
+	// weren't compared
+	Score *float64 `json:"score,omitempty"`
+
+	// Desired holds the rules rendered for this repo, for apply
+	Desired config.Rules `json:"-"`
+}
+
+// FullName returns the repo as owner/name
+func (r RepoAuditResult) FullName() string {
+	return r.Owner + "/" + r.Repo
+}
+
+// Compliant reports whether the repo matched every rule
+func (r RepoAuditResult) Compliant() bool {
+	return r.Status == StatusCompliant
+}
+
+// Checked reports whether the repo counts towards compliance: it was
+// compared against the rules, or should have been but errored
+func (r RepoAuditResult) Checked() bool {
+	return r.Status == StatusCompliant || r.Status == StatusNonCompliant || r.Status == StatusError
+}
+
+// NotChecked reports whether the repo was listed but deliberately not
+// compared: skipped, archived, without the branch or inactive
+func (r RepoAuditResult) NotChecked() bool {
+	return !r.Checked()
 }
 
 var auditCmd = &cobra.Command{
 	Use:   "audit",
 	Short: "Check repos against branch protection config",
-	Long:  `Audits GitHub repos for a user or organization against the rules defined in rampart.yaml. Exits non-zero if any repos are non-compliant.`,
+	Long:  `Audits GitHub repos for one or more users or organizations against the rules defined in rampart.yaml. Exits non-zero if any repos are non-compliant, or with --fail-on, if any rule of at least that severity fails.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		owner, _ := cmd.Flags().GetString("owner")
+		owners, _ := cmd.Flags().GetStringSlice("owner")
+		allOrgs, _ := cmd.Flags().GetBool("all-orgs")
+		includeForks, _ := cmd.Flags().GetBool("include-forks")
+		includeArchived, _ := cmd.Flags().GetBool("include-archived")
 		repo, _ := cmd.Flags().GetString("repo")
+		reposFile, _ := cmd.Flags().GetString("repos-file")
 		exclude, _ := cmd.Flags().GetStringSlice("exclude")
 		configPath, _ := cmd.Flags().GetString("config")
 		reportPath, _ := cmd.Flags().GetString("report")
+		csvPath, _ := cmd.Flags().GetString("csv")
+		historyPath, _ := cmd.Flags().GetString("history")
+		format, _ := cmd.Flags().GetString("format")
+		failOn, _ := cmd.Flags().GetString("fail-on")
+		minScore, _ := cmd.Flags().GetFloat64("min-score")
 
-		if owner == "" {
-			// Default to current user
-			user, err := github.GetCurrentUser()
-			if err != nil {
-				exitWithError(err.Error())
-			}
-			owner = user
+		if !config.ValidSeverity(failOn) {
+			exitWithError(fmt.Sprintf("invalid --fail-on %q (valid: low, medium, high, critical)", failOn))
+		}
+		if allOrgs && (len(owners) > 0 || repo != "") {
+			exitWithError("--all-orgs can't be combined with --owner or --repo")
 		}
+		setOutputFormat(format, formatText, formatJSON, formatSARIF, formatJUnit, formatMarkdown, formatCSV)
 
-		results, cfg := auditRepos(owner, repo, configPath, exclude)
+		run := auditRepos(auditOptions{
+			Owners:          owners,
+			AllOrgs:         allOrgs,
+			Repo:            repo,
+			ConfigPath:      configPath,
+			Exclude:         exclude,
+			IncludeForks:    includeForks,
+			IncludeArchived: includeArchived,
+			ReposFile:       reposFile,
+		})
+		cfg, results := run.Config, run.Results
+		summary := summarize(results)
 
-		// Print results
-		nonCompliant := 0
-		for _, r := range results {
-			if r.Skipped {
-				fmt.Printf("  - %s (skipped: %s)\n", r.Repo, r.Error)
-				continue
+		switch format {
+		case formatJSON:
+			if err := writeJSON(os.Stdout, newAuditJSON(configPath, run)); err != nil {
+				exitWithError(err.Error())
 			}
-			if r.Error != "" {
-				fmt.Printf("  x %s (error: %s)\n", r.Repo, r.Error)
-				nonCompliant++
-				continue
+		case formatSARIF:
+			if err := writeSARIF(os.Stdout, configPath, cfg, results); err != nil {
+				exitWithError(err.Error())
 			}
-			if r.Compliant {
-				fmt.Printf("  ✓ %s\n", r.Repo)
-			} else {
-				fmt.Printf("  ✗ %s\n", r.Repo)
-				nonCompliant++
-				for _, d := range r.Diffs {
-					if !d.Pass {
-						fmt.Printf("      %s: want %s, got %s\n", d.Rule, d.Want, d.Got)
-					}
-				}
+		case formatJUnit:
+			if err := writeJUnit(os.Stdout, results); err != nil {
+				exitWithError(err.Error())
+			}
+		case formatMarkdown:
+			if err := writeMarkdown(os.Stdout, configPath, run); err != nil {
+				exitWithError(err.Error())
 			}
+		case formatCSV:
+			if err := writeCSV(os.Stdout, results); err != nil {
+				exitWithError(err.Error())
+			}
+		default:
+			printAuditResults(run, summary, cfg.ActiveExemptions(time.Now()))
 		}
 
-		fmt.Println()
-		total := len(results)
-		compliant := total - nonCompliant
-		skipped := 0
-		for _, r := range results {
-			if r.Skipped {
-				skipped++
-				compliant--
+		var trend *ReportHistory
+		if historyPath != "" {
+			var err error
+			trend, err = recordHistory(historyPath, run.OwnerLabel(), configPath, results)
+			if err != nil {
+				exitWithError(err.Error())
+			}
+			if format == formatText && trend.Previous != nil {
+				fmt.Println()
+				printHistoryChanges(*trend.Previous, history.Changes{
+					NewlyNonCompliant: trend.NewlyNonCompliant,
+					Fixed:             trend.Fixed,
+				})
 			}
 		}
-		fmt.Printf("Results: %d compliant, %d non-compliant, %d skipped out of %d repos\n",
-			compliant, nonCompliant, skipped, total)
+
+		if err := reportToActions(configPath, run); err != nil {
+			exitWithError(err.Error())
+		}
 
 		if reportPath != "" {
-			data := newReportData(owner, configPath, cfg.Branch, results)
+			data := newReportData(configPath, run)
+			data.History = trend
+			data.Exemptions = cfg.ActiveExemptions(time.Now())
 			if err := generateReport(reportPath, data); err != nil {
 				exitWithError(err.Error())
 			}
-			fmt.Printf("\nReport written to %s\n", reportPath)
+			fmt.Fprintf(statusOut, "\nReport written to %s\n", reportPath)
+		}
+
+		if csvPath != "" {
+			if err := generateCSV(csvPath, results); err != nil {
+				exitWithError(err.Error())
+			}
+			fmt.Fprintf(statusOut, "\nCSV written to %s\n", csvPath)
 		}
 
-		if nonCompliant > 0 {
+		if minScore > 0 && (summary.Score == nil || *summary.Score < minScore) {
+			fmt.Fprintf(os.Stderr, "Compliance score %s is below --min-score %g\n", formatScore(summary.Score), minScore)
+			os.Exit(1)
+		}
+		if summary.Errors > 0 || failsThreshold(results, failOn) {
 			os.Exit(1)
 		}
 	},
 }
 
+// formatScore renders a compliance score for display
+func formatScore(score *float64) string {
+	if score == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f", *score)
+}
+
+// failsThreshold reports whether any repo has a failing rule at or above the
+// --fail-on severity
+func failsThreshold(results []RepoAuditResult, failOn string) bool {
+	for _, r := range results {
+		for _, d := range config.Failing(r.Diffs) {
+			if config.SeverityAtLeast(d.Severity, failOn) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// printAuditResults prints one line per repo and the totals. When several
+// owners were audited, repos are grouped under their owner with a subtotal
+// for each.
+func printAuditResults(run auditRun, summary auditSummary, exemptions []config.Exemption) {
+	if len(run.Owners) > 1 {
+		for _, o := range summarizeOwners(run) {
+			fmt.Printf("%s:\n", o.Owner)
+			for _, r := range run.Results {
+				if r.Owner == o.Owner {
+					printRepoResult(r)
+				}
+			}
+			fmt.Printf("  %s\n\n", formatCounts(o.Summary))
+		}
+	} else {
+		for _, r := range run.Results {
+			printRepoResult(r)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Results: %s\n", formatCounts(summary))
+
+	if len(run.SkippedOwners) > 0 {
+		fmt.Printf("\nSkipped owners (%d):\n", len(run.SkippedOwners))
+		for _, s := range run.SkippedOwners {
+			fmt.Printf("  - %s: %s\n", s.Owner, s.Reason)
+		}
+	}
+
+	if len(exemptions) > 0 {
+		fmt.Printf("\nActive exemptions (%d):\n", len(exemptions))
+		for _, e := range exemptions {
+			fmt.Printf("  %s: %s (approved by %s, expires %s, %d days left)\n",
+				exemptionScope(e), e.Reason, e.Approver, e.Expires, e.DaysRemaining(time.Now()))
+		}
+	}
+}
+
+func printRepoResult(r RepoAuditResult) {
+	switch r.Status {
+	case StatusSkipped:
+		fmt.Printf("  - %s (skipped: %s)\n", r.Repo, r.SkipReason)
+	case StatusArchived:
+		fmt.Printf("  - %s (archived: %s)\n", r.Repo, r.SkipReason)
+	case StatusNoBranch:
+		fmt.Printf("  - %s (no branch: %s)\n", r.Repo, r.SkipReason)
+	case StatusInactive:
+		fmt.Printf("  - %s (inactive: %s)\n", r.Repo, r.SkipReason)
+	case StatusError:
+		fmt.Printf("  x %s (error: %s)\n", r.Repo, r.Error)
+	case StatusCompliant:
+		fmt.Printf("  ✓ %s%s\n", r.Repo, policyNote(r))
+	default:
+		fmt.Printf("  ✗ %s (score %s)%s\n", r.Repo, formatScore(r.Score), policyNote(r))
+		for _, d := range config.Failing(r.Diffs) {
+			fmt.Printf("      %s [%s]: want %s, got %s\n", d.Rule, d.Severity, d.Want, d.Got)
+		}
+	}
+	if repoRules := ruleNamesWhere(r.Diffs, func(d config.RuleDiff) bool { return d.Source == config.SourceRepo }); len(repoRules) > 0 {
+		fmt.Printf("      repo policy sets: %s\n", strings.Join(repoRules, ", "))
+	}
+	if exempt := ruleNamesWhere(r.Diffs, func(d config.RuleDiff) bool { return d.Exempt }); len(exempt) > 0 {
+		fmt.Printf("      exempt: %s\n", strings.Join(exempt, ", "))
+	}
+	for _, w := range r.Warnings {
+		fmt.Printf("      warning: %s\n", w)
+	}
+}
+
+// policyNote names the policy a repo was held to, if any
+func policyNote(r RepoAuditResult) string {
+	if r.Policy == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [policy: %s]", r.Policy)
+}
+
+// formatCounts renders the totals shown after the repo list. Archived,
+// branchless and inactive repos are only mentioned when there are some.
+func formatCounts(s auditSummary) string {
+	counts := fmt.Sprintf("%d compliant, %d non-compliant, %d skipped", s.Compliant, s.NonCompliant+s.Errors, s.Skipped)
+	if s.Archived > 0 {
+		counts += fmt.Sprintf(", %d archived", s.Archived)
+	}
+	if s.NoBranch > 0 {
+		counts += fmt.Sprintf(", %d without the branch", s.NoBranch)
+	}
+	if s.Inactive > 0 {
+		counts += fmt.Sprintf(", %d inactive", s.Inactive)
+	}
+	return fmt.Sprintf("%s out of %d repos (compliance score %s)", counts, s.Total, formatScore(s.Score))
+}
+
+// exemptionScope names what an exemption covers, e.g. "api" or "api/enforce_admins"
+func exemptionScope(e config.Exemption) string {
+	if e.Rule == "" {
+		return e.Repo
+	}
+	return e.Repo + "/" + e.Rule
+}
+
+// ruleNamesWhere lists the rules of the diffs matching keep
+func ruleNamesWhere(diffs []config.RuleDiff, keep func(config.RuleDiff) bool) []string {
+	var rules []string
+	for _, d := range diffs {
+		if keep(d) {
+			rules = append(rules, d.Rule)
+		}
+	}
+	return rules
+}
+
 func init() {
-	auditCmd.Flags().String("owner", "", "GitHub user or org to audit (defaults to authenticated user)")
+	auditCmd.Flags().StringSlice("owner", nil, "GitHub users or orgs to audit, comma-separated (defaults to owners in the config, then the authenticated user)")
+	auditCmd.Flags().Bool("all-orgs", false, "Audit every organization you administer")
 	auditCmd.Flags().String("repo", "", "Audit a single repo instead of all repos")
-	auditCmd.Flags().StringSlice("exclude", nil, "Repos to exclude (repeatable)")
+	auditCmd.Flags().String("repos-file", "", "Audit exactly the owner/name repos listed in this file, one per line or as a JSON array (- for stdin)")
+	auditCmd.Flags().Bool("include-forks", false, "Audit forks too (also select.include_forks in the config)")
+	auditCmd.Flags().Bool("include-archived", false, "List archived repos as read-only (also select.include_archived in the config)")
+	auditCmd.Flags().StringSlice("exclude", nil, "Repos to exclude, as name or owner/name (repeatable)")
 	auditCmd.Flags().String("config", "rampart.yaml", "Path to config file")
 	auditCmd.Flags().String("report", "", "Write an HTML report to the given file path")
+	auditCmd.Flags().String("csv", "", "Write a CSV compliance matrix to the given file path")
+	auditCmd.Flags().String("history", "", "Append this run to a JSON-lines history file and show changes since the last run")
+	auditCmd.Flags().String("fail-on", config.SeverityLow, "Exit non-zero only for failing rules at or above this severity: low, medium, high or critical")
+	auditCmd.Flags().Var(new(scoreFlag), "min-score", "Exit non-zero if the overall compliance score (0-100) is below this")
+	auditCmd.Flags().String("format", formatText, "Output format: text, json, sarif, junit, markdown or csv")
+}
+
+// scoreFlag is a compliance score flag, rejected at parse time unless it's
+// between 0 and 100
+type scoreFlag float64
+
+func (f *scoreFlag) String() string {
+	return strconv.FormatFloat(float64(*f), 'g', -1, 64)
+}
+
+func (f *scoreFlag) Set(s string) error {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	if !(v >= 0 && v <= 100) {
+		return fmt.Errorf("must be between 0 and 100, got %s", s)
+	}
+	*f = scoreFlag(v)
+	return nil
+}
+
+func (f *scoreFlag) Type() string {
+	return "float64"
+}
+
+// auditOptions selects the repos auditRepos audits
+type auditOptions struct {
+	// Owners to audit; empty falls back to the config's owners, then the
+	// authenticated user
+	Owners []string
+	// AllOrgs audits every organization the authenticated user administers
+	AllOrgs    bool
+	Repo       string
+	ConfigPath string
+	Exclude    []string
+	// IncludeForks and IncludeArchived add to the config's select settings
+	IncludeForks    bool
+	IncludeArchived bool
+	// ReposFile lists the exact repos to audit, as owner/name; "-" is stdin
+	ReposFile string
+}
+
+// auditRun is the outcome of auditRepos
+type auditRun struct {
+	Config config.Config
+	// Owners lists the owners whose repos were audited
+	Owners  []string
+	Results []RepoAuditResult
+	// SkippedOwners lists owners found by --all-orgs that couldn't be audited
+	SkippedOwners []ownerSkip
+}
+
+// ownerSkip records why an owner was left out of the audit
+type ownerSkip struct {
+	Owner  string `json:"owner"`
+	Reason string `json:"reason"`
+}
+
+// OwnerLabel names the audited owners in reports and history, e.g. "acme,globex"
+func (a auditRun) OwnerLabel() string {
+	return strings.Join(a.Owners, ",")
 }
 
 // auditRepos is the shared audit engine used by both audit and apply commands
-func auditRepos(owner, repo, configPath string, exclude []string) ([]RepoAuditResult, config.Config) {
-	cfg, err := config.Load(configPath)
+func auditRepos(opts auditOptions) auditRun {
+	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		exitWithError(err.Error())
 	}
 
-	var repos []github.Repo
-	if repo != "" {
-		if cfg.Branch == "default" {
-			r, err := github.GetRepo(owner, repo)
-			if err != nil {
-				exitWithError(err.Error())
-			}
-			repos = []github.Repo{r}
-		} else {
-			repos = []github.Repo{{Name: repo}}
+	var targets []auditTarget
+	var owners []string
+	var skipped []ownerSkip
+	if opts.ReposFile != "" {
+		if len(opts.Owners) > 0 || opts.AllOrgs || opts.Repo != "" {
+			exitWithError("--repos-file can't be combined with --owner, --all-orgs or --repo")
 		}
+		targets, owners = fileTargets(opts.ReposFile)
 	} else {
-		fmt.Printf("Fetching repos for %s...\n", owner)
-		repos, err = github.ListRepos(owner)
-		if err != nil {
-			exitWithError(err.Error())
-		}
+		targets, owners, skipped = ownerTargets(cfg, opts)
 	}
 
+	targets = selectByProperties(cfg, targets)
+
 	excludeSet := make(map[string]bool)
-	for _, e := range exclude {
+	for _, e := range opts.Exclude {
 		excludeSet[e] = true
 	}
 
-	fmt.Printf("Auditing %d repos against %s (branch: %s)\n\n", len(repos), configPath, cfg.Branch)
+	fmt.Fprintf(statusOut, "Auditing %d repos against %s (branch: %s)\n\n", len(targets), opts.ConfigPath, cfg.Branch)
 
 	var results []RepoAuditResult
-	for _, r := range repos {
-		if excludeSet[r.Name] {
+	for _, t := range targets {
+		switch {
+		case excludeSet[t.repo.Name] || excludeSet[t.owner+"/"+t.repo.Name]:
 			results = append(results, RepoAuditResult{
-				Repo:    r.Name,
-				Skipped: true,
-				Error:   "excluded",
+				Owner:      t.owner,
+				Repo:       t.repo.Name,
+				Status:     StatusSkipped,
+				SkipReason: "excluded",
 			})
-			continue
+		case t.err != nil:
+			results = append(results, RepoAuditResult{
+				Owner:  t.owner,
+				Repo:   t.repo.Name,
+				Status: StatusError,
+				Error:  t.err.Error(),
+			})
+		case t.skip != "":
+			results = append(results, RepoAuditResult{
+				Owner:      t.owner,
+				Repo:       t.repo.Name,
+				Status:     StatusSkipped,
+				SkipReason: t.skip,
+			})
+		default:
+			results = append(results, auditRepo(cfg, t.owner, t.repo, t.props))
 		}
+	}
 
-		branch := cfg.Branch
-		if branch == "default" {
-			branch = r.DefaultBranch
-		}
+	return auditRun{Config: cfg, Owners: owners, Results: results, SkippedOwners: skipped}
+}
 
-		actual, ok, err := github.GetBranchProtection(owner, r.Name, branch)
+// auditTarget is a repo queued for auditing. err is set when the repo
+// couldn't be looked up, and is reported as that repo's error; skip is set
+// when the repo is reported as skipped instead of audited.
+type auditTarget struct {
+	owner string
+	repo  github.Repo
+	props map[string][]string
+	err   error
+	skip  string
+	// listed is set for repos named by --repo or --repos-file
+	listed bool
+}
+
+// ownerTargets lists the repos of the owners selected by opts, falling back
+// to the config's owners and then the authenticated user. It returns the
+// owners whose repos were listed, and with --all-orgs, the orgs left out.
+func ownerTargets(cfg config.Config, opts auditOptions) ([]auditTarget, []string, []ownerSkip) {
+	var skipped []ownerSkip
+	owners := opts.Owners
+	if opts.AllOrgs {
+		owners, skipped = adminOrgs()
+		if len(owners) == 0 {
+			exitWithError(fmt.Sprintf("found no organizations you administer (%d skipped)", len(skipped)))
+		}
+	}
+	if len(owners) == 0 {
+		owners = cfg.Owners
+	}
+	if len(owners) == 0 {
+		user, err := github.GetCurrentUser()
 		if err != nil {
-			results = append(results, RepoAuditResult{
-				Repo:  r.Name,
-				Error: err.Error(),
-			})
+			exitWithError(err.Error())
+		}
+		owners = []string{user}
+	}
+	if opts.Repo != "" && len(owners) > 1 {
+		exitWithError("--repo needs a single owner")
+	}
+
+	listOpts := github.ListOptions{
+		IncludeForks:    opts.IncludeForks || cfg.Select.IncludeForks,
+		IncludeArchived: opts.IncludeArchived || cfg.Select.IncludeArchived,
+	}
+	var targets []auditTarget
+	var listed []string
+	for _, owner := range owners {
+		var repos []github.Repo
+		if opts.Repo != "" {
+			if cfg.Branch == "default" {
+				r, err := github.GetRepo(owner, opts.Repo)
+				if err != nil {
+					exitWithError(err.Error())
+				}
+				repos = []github.Repo{r}
+			} else {
+				repos = []github.Repo{{Name: opts.Repo}}
+			}
+		} else {
+			fmt.Fprintf(statusOut, "Fetching repos for %s...\n", owner)
+			var err error
+			repos, err = github.ListRepos(owner, listOpts)
+			if err != nil && opts.AllOrgs {
+				// One org with SSO or permission trouble shouldn't stop the rest
+				fmt.Fprintf(statusOut, "  skipping %s: %s\n", owner, err)
+				skipped = append(skipped, ownerSkip{Owner: owner, Reason: err.Error()})
+				continue
+			}
+			if err != nil {
+				exitWithError(err.Error())
+			}
+		}
+		listed = append(listed, owner)
+		for _, r := range repos {
+			targets = append(targets, auditTarget{owner: owner, repo: r, listed: opts.Repo != ""})
+		}
+	}
+	return targets, listed, skipped
+}
+
+// selectByProperties attaches each repo's custom property values and drops
+// the repos select.properties doesn't match. Properties are only fetched,
+// once per owner, when the config selects repos or assigns policies by them.
+// Repos that couldn't be looked up keep their error, and repos named by
+// --repo or --repos-file are reported as skipped rather than dropped.
+func selectByProperties(cfg config.Config, targets []auditTarget) []auditTarget {
+	if !cfg.UsesProperties() {
+		return targets
+	}
+
+	byOwner := make(map[string]map[string]map[string][]string)
+	errs := make(map[string]error)
+	var selected []auditTarget
+	for _, t := range targets {
+		if t.err != nil {
+			selected = append(selected, t)
 			continue
 		}
-		if !ok {
-			results = append(results, RepoAuditResult{
-				Repo:    r.Name,
-				Skipped: true,
-				Error:   "insufficient permissions",
-			})
+		props, fetched := byOwner[t.owner]
+		err := errs[t.owner]
+		if !fetched && err == nil {
+			fmt.Fprintf(statusOut, "Fetching custom properties for %s...\n", t.owner)
+			props, err = github.GetRepoProperties(t.owner)
+			byOwner[t.owner], errs[t.owner] = props, err
+		}
+		if err != nil {
+			t.err = fmt.Errorf("reading custom properties: %w", err)
+			selected = append(selected, t)
 			continue
 		}
 
-		diffs := config.Compare(cfg.Rules, actual)
-		compliant := true
-		for _, d := range diffs {
-			if !d.Pass {
-				compliant = false
-				break
-			}
+		t.props = props[t.repo.Name]
+		switch {
+		case config.MatchProperties(cfg.Select.Properties, t.props):
+			selected = append(selected, t)
+		case t.listed:
+			t.skip = "doesn't match select.properties"
+			selected = append(selected, t)
 		}
+	}
+	if dropped := len(targets) - len(selected); dropped > 0 {
+		fmt.Fprintf(statusOut, "Left out %d repos not matching select.properties\n", dropped)
+	}
+	return selected
+}
 
-		results = append(results, RepoAuditResult{
-			Repo:      r.Name,
-			Branch:    branch,
-			Compliant: compliant,
-			Diffs:     diffs,
-		})
+// adminOrgs lists the organizations the authenticated user administers.
+// Orgs where the user is only a member, or hasn't accepted the invitation,
+// are returned as skipped.
+func adminOrgs() ([]string, []ownerSkip) {
+	fmt.Fprintln(statusOut, "Fetching your organizations...")
+	memberships, err := github.ListOrgMemberships()
+	if err != nil {
+		exitWithError(err.Error())
+	}
+
+	var orgs []string
+	var skipped []ownerSkip
+	for _, m := range memberships {
+		org := m.Organization.Login
+		switch {
+		case m.State != "active":
+			skipped = append(skipped, ownerSkip{Owner: org, Reason: "membership is " + m.State})
+		case m.Role != "admin":
+			skipped = append(skipped, ownerSkip{Owner: org, Reason: "not an org admin (role: " + m.Role + ")"})
+		default:
+			orgs = append(orgs, org)
+		}
+	}
+	return orgs, skipped
+}
+
+// auditRepo compares one repo's branch protection against the config, using
+// the rules of the first policy its custom properties match
+func auditRepo(cfg config.Config, owner string, r github.Repo, props map[string][]string) RepoAuditResult {
+	base := cfg.Rules
+	var policy string
+	if p := cfg.PolicyFor(props); p != nil {
+		rules, err := cfg.PolicyRules(*p)
+		if err != nil {
+			return RepoAuditResult{Owner: owner, Repo: r.Name, Policy: p.Name, Status: StatusError, Error: err.Error()}
+		}
+		base, policy = rules, p.Name
+	}
+
+	if r.Archived {
+		return RepoAuditResult{Owner: owner, Repo: r.Name, Policy: policy, Status: StatusArchived, SkipReason: "read-only"}
+	}
+	if reason := cfg.Select.Inactive(r.PushedAt, r.CreatedAt, time.Now()); reason != "" {
+		return RepoAuditResult{Owner: owner, Repo: r.Name, Policy: policy, Status: StatusInactive, SkipReason: reason}
+	}
+	branch := cfg.Branch
+	if branch == "default" {
+		branch = r.DefaultBranch
+	}
+	if branch == "" {
+		return RepoAuditResult{Owner: owner, Repo: r.Name, Policy: policy, Status: StatusNoBranch, SkipReason: "repository is empty"}
+	}
+	result := RepoAuditResult{Owner: owner, Repo: r.Name, Branch: branch, Policy: policy}
+
+	desired, sources, warnings, err := effectiveRules(cfg, base, owner, r.Name, branch)
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err.Error()
+		return result
+	}
+
+	actual, ok, err := github.GetBranchProtection(owner, r.Name, branch)
+	if errors.Is(err, github.ErrBranchNotFound) {
+		result.Status = StatusNoBranch
+		result.SkipReason = fmt.Sprintf("branch %s not found", branch)
+		return result
+	}
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err.Error()
+		return result
+	}
+	if !ok {
+		result.Branch = ""
+		result.Status = StatusSkipped
+		result.SkipReason = "insufficient permissions"
+		return result
+	}
+
+	diffs := config.Compare(desired, actual)
+	for i := range diffs {
+		diffs[i].Source = sources[diffs[i].Rule]
+		diffs[i].Severity = cfg.Severity(diffs[i].Rule)
+	}
+	warnings = append(warnings, config.ApplyExemptions(cfg.Exemptions, owner, r.Name, diffs, &desired, actual, time.Now())...)
+	result.Status = StatusCompliant
+	if len(config.Failing(diffs)) > 0 {
+		result.Status = StatusNonCompliant
+	}
+	score := cfg.Score(diffs)
+
+	result.Diffs = diffs
+	result.Warnings = warnings
+	result.Score = &score
+	result.Desired = desired
+	return result
+}
+
+// effectiveRules works out the rules a repo is held to: the central rules
+// (the base rules, or its policy's), merged with the repo's own policy file
+// when repo_config is enabled, then rendered for the repo. sources is nil
+// unless repo_config is enabled.
+func effectiveRules(cfg config.Config, central config.Rules, owner, repo, branch string) (config.Rules, map[string]string, []string, error) {
+	rules := central
+	var sources map[string]string
+	var warnings []string
+
+	if cfg.RepoConfig.Enabled {
+		path := cfg.RepoConfig.FilePath()
+		data, err := github.GetFileContents(owner, repo, path, "")
+		switch {
+		case errors.Is(err, github.ErrNotFound):
+			sources = make(map[string]string, len(config.RuleNames))
+			for _, rule := range config.RuleNames {
+				sources[rule] = config.SourceCentral
+			}
+		case err != nil:
+			return config.Rules{}, nil, nil, fmt.Errorf("reading %s: %w", path, err)
+		default:
+			ov, err := config.ApplyRepoOverride(rules, data, cfg.RepoConfig.TightenOnly)
+			if err != nil {
+				return config.Rules{}, nil, nil, fmt.Errorf("%s: %w", path, err)
+			}
+			rules, sources, warnings = ov.Rules, ov.Sources, ov.Rejected
+		}
 	}
 
-	return results, cfg
+	rendered, err := rules.ForRepo(config.RepoVars{Owner: owner, Repo: repo, Branch: branch})
+	if err != nil {
+		return config.Rules{}, nil, nil, err
+	}
+	return rendered, sources, warnings, nil
 }
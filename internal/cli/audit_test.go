@@ -0,0 +1,29 @@
+package cli
+
+import "testing"
+
+func TestScoreFlag(t *testing.T) {
+	for _, tt := range []struct {
+		in string
+		ok bool
+	}{
+		{"0", true},
+		{"87.5", true},
+		{"100", true},
+		{"-1", false},
+		{"100.1", false},
+		{"NaN", false},
+		{"high", false},
+	} {
+		var f scoreFlag
+		if err := f.Set(tt.in); (err == nil) != tt.ok {
+			t.Errorf("Set(%q) error = %v, want ok %v", tt.in, err, tt.ok)
+		}
+	}
+
+	auditCmd.Flags().Set("min-score", "90")
+	t.Cleanup(func() { auditCmd.Flags().Set("min-score", "0") })
+	if got, err := auditCmd.Flags().GetFloat64("min-score"); err != nil || got != 90 {
+		t.Errorf("GetFloat64(min-score) = %v, %v; want 90", got, err)
+	}
+}
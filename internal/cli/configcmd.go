@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/wdm0006/rampart/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect rampart config files",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the fully resolved config",
+	Long:  `Loads a config, resolving its extends chain, and prints the effective config that audit and apply would use.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath, _ := cmd.Flags().GetString("config")
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+
+		data, err := yaml.Marshal(&cfg)
+		if err != nil {
+			exitWithError(fmt.Sprintf("failed to marshal config: %s", err))
+		}
+		fmt.Print(string(data))
+	},
+}
+
+func init() {
+	configShowCmd.Flags().String("config", "rampart.yaml", "Path to config file")
+	configCmd.AddCommand(configShowCmd)
+}
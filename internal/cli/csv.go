@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+// writeCSV writes a compliance matrix with one row per repo and one column
+// per rule, in the order Compare reports them. Repos are named owner/name
+// when the results span several owners. Rules that weren't compared
+// (e.g. required_approvals when PRs aren't required) are left blank.
+func writeCSV(w io.Writer, results []RepoAuditResult) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"repo", "branch", "status"}
+	header = append(header, config.RuleNames...)
+	header = append(header, "error", "skip_reason", "score", "policy")
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	multiOwner := spansOwners(results)
+	for _, r := range results {
+		cells := make(map[string]string, len(r.Diffs))
+		for _, d := range r.Diffs {
+			if d.Exempt {
+				cells[d.Rule] = "exempt"
+			} else if d.Pass {
+				cells[d.Rule] = "pass"
+			} else {
+				cells[d.Rule] = fmt.Sprintf("fail (want %s, got %s)", d.Want, d.Got)
+			}
+		}
+
+		row := []string{repoLabel(r, multiOwner), r.Branch, r.Status}
+		for _, name := range config.RuleNames {
+			row = append(row, cells[name])
+		}
+		score := ""
+		if r.Score != nil {
+			score = fmt.Sprintf("%.1f", *r.Score)
+		}
+		row = append(row, r.Error, r.SkipReason, score, r.Policy)
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+func generateCSV(path string, results []RepoAuditResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating CSV file: %w", err)
+	}
+	defer f.Close()
+
+	return writeCSV(f, results)
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+// repoChange describes how one repo differs between two audit runs
+type repoChange struct {
+	// Repo is owner/name
+	Repo      string
+	OldStatus string
+	NewStatus string
+	// NewlyFailing and Fixed list rule diffs from the new and old runs respectively
+	NewlyFailing []config.RuleDiff
+	Fixed        []config.RuleDiff
+}
+
+// auditDrift groups repo changes between two audit runs
+type auditDrift struct {
+	Regressed []repoChange
+	Improved  []repoChange
+	Added     []repoChange
+	Removed   []repoChange
+	Changed   []repoChange
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD.json NEW.json",
+	Short: "Compare two saved JSON audits",
+	Long: `Compares two audit results saved with 'rampart audit --format json' and reports repos whose
+protection regressed, improved, were added or disappeared. Exits non-zero if any repo regressed.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		oldRun, err := loadAuditJSON(args[0])
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		newRun, err := loadAuditJSON(args[1])
+		if err != nil {
+			exitWithError(err.Error())
+		}
+
+		drift := compareAudits(oldRun.Results, newRun.Results)
+
+		printChanges("Regressed", "✗", drift.Regressed)
+		printChanges("Improved", "✓", drift.Improved)
+		printChanges("Added", "+", drift.Added)
+		printChanges("Removed", "-", drift.Removed)
+		printChanges("Changed", "~", drift.Changed)
+
+		fmt.Printf("Drift: %d regressed, %d improved, %d added, %d removed, %d changed\n",
+			len(drift.Regressed), len(drift.Improved), len(drift.Added), len(drift.Removed), len(drift.Changed))
+
+		if len(drift.Regressed) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func loadAuditJSON(path string) (auditJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return auditJSON{}, fmt.Errorf("failed to read audit: %w", err)
+	}
+	var out auditJSON
+	if err := json.Unmarshal(data, &out); err != nil {
+		return auditJSON{}, fmt.Errorf("failed to parse audit %s: %w", path, err)
+	}
+	if out.SchemaVersion > jsonSchemaVersion {
+		return auditJSON{}, fmt.Errorf("%s uses schema version %d; this rampart supports up to %d",
+			path, out.SchemaVersion, jsonSchemaVersion)
+	}
+	return out, nil
+}
+
+// compareAudits classifies every repo present in either run. A repo regresses
+// when a rule fails that passed before, or a compliant repo can no longer be
+// audited; it improves when rules were fixed and none newly fail. Moves into
+// or out of skipped, and from errored to non-compliant, are reported as
+// changed.
+func compareAudits(oldResults, newResults []RepoAuditResult) auditDrift {
+	oldByRepo := make(map[string]RepoAuditResult, len(oldResults))
+	for _, r := range oldResults {
+		oldByRepo[r.FullName()] = r
+	}
+	newByRepo := make(map[string]RepoAuditResult, len(newResults))
+	for _, r := range newResults {
+		newByRepo[r.FullName()] = r
+	}
+
+	var drift auditDrift
+	for _, cur := range newResults {
+		prev, ok := oldByRepo[cur.FullName()]
+		if !ok {
+			drift.Added = append(drift.Added, repoChange{Repo: cur.FullName(), NewStatus: cur.Status})
+			continue
+		}
+
+		c := repoChange{Repo: cur.FullName(), OldStatus: prev.Status, NewStatus: cur.Status}
+		compared := func(s string) bool { return s == StatusCompliant || s == StatusNonCompliant }
+
+		switch {
+		case compared(prev.Status) && compared(cur.Status):
+			c.NewlyFailing = failingNotIn(cur.Diffs, prev.Diffs)
+			c.Fixed = failingNotIn(prev.Diffs, cur.Diffs)
+			if len(c.NewlyFailing) > 0 {
+				drift.Regressed = append(drift.Regressed, c)
+			} else if len(c.Fixed) > 0 {
+				drift.Improved = append(drift.Improved, c)
+			}
+		case prev.Status == StatusCompliant && cur.Status == StatusError:
+			drift.Regressed = append(drift.Regressed, c)
+		case prev.Status == StatusError && cur.Status == StatusCompliant:
+			drift.Improved = append(drift.Improved, c)
+		case prev.Status != cur.Status:
+			drift.Changed = append(drift.Changed, c)
+		}
+	}
+
+	for _, prev := range oldResults {
+		if _, ok := newByRepo[prev.FullName()]; !ok {
+			drift.Removed = append(drift.Removed, repoChange{Repo: prev.FullName(), OldStatus: prev.Status})
+		}
+	}
+
+	for _, list := range [][]repoChange{drift.Regressed, drift.Improved, drift.Added, drift.Removed, drift.Changed} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Repo < list[j].Repo })
+	}
+	return drift
+}
+
+// failingNotIn returns the failing diffs in a whose rule isn't failing in b
+func failingNotIn(a, b []config.RuleDiff) []config.RuleDiff {
+	failingInB := make(map[string]bool)
+	for _, d := range config.Failing(b) {
+		failingInB[d.Rule] = true
+	}
+	var out []config.RuleDiff
+	for _, d := range config.Failing(a) {
+		if !failingInB[d.Rule] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func printChanges(title, mark string, changes []repoChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", title, len(changes))
+	for _, c := range changes {
+		switch {
+		case c.OldStatus == "":
+			fmt.Printf("  %s %s (%s)\n", mark, c.Repo, c.NewStatus)
+		case c.NewStatus == "":
+			fmt.Printf("  %s %s (was %s)\n", mark, c.Repo, c.OldStatus)
+		case len(c.NewlyFailing) > 0 || len(c.Fixed) > 0:
+			fmt.Printf("  %s %s\n", mark, c.Repo)
+			for _, d := range c.NewlyFailing {
+				fmt.Printf("      now failing %s: want %s, got %s\n", d.Rule, d.Want, d.Got)
+			}
+			if len(c.Fixed) > 0 {
+				var rules []string
+				for _, d := range c.Fixed {
+					rules = append(rules, d.Rule)
+				}
+				fmt.Printf("      fixed: %s\n", strings.Join(rules, ", "))
+			}
+		default:
+			fmt.Printf("  %s %s: %s → %s\n", mark, c.Repo, c.OldStatus, c.NewStatus)
+		}
+	}
+	fmt.Println()
+}
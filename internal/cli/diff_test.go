@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+func TestCompareAudits(t *testing.T) {
+	fail := func(rule string) config.RuleDiff {
+		return config.RuleDiff{Rule: rule, Pass: false, Want: "true", Got: "false"}
+	}
+	pass := func(rule string) config.RuleDiff {
+		return config.RuleDiff{Rule: rule, Pass: true, Want: "true", Got: "true"}
+	}
+	repo := func(name, status string, diffs ...config.RuleDiff) RepoAuditResult {
+		return RepoAuditResult{Owner: "acme", Repo: name, Status: status, Diffs: diffs}
+	}
+
+	tests := []struct {
+		name string
+		old  RepoAuditResult
+		new  RepoAuditResult
+		// want is the drift bucket the repo lands in, or "" for none
+		want string
+	}{
+		{"compliant to non-compliant", repo("a", StatusCompliant, pass("enforce_admins")), repo("a", StatusNonCompliant, fail("enforce_admins")), "regressed"},
+		{"non-compliant to compliant", repo("a", StatusNonCompliant, fail("enforce_admins")), repo("a", StatusCompliant, pass("enforce_admins")), "improved"},
+		{"another rule fails", repo("a", StatusNonCompliant, fail("enforce_admins"), pass("allow_deletions")), repo("a", StatusNonCompliant, fail("enforce_admins"), fail("allow_deletions")), "regressed"},
+		{"one fixed, one newly failing", repo("a", StatusNonCompliant, fail("enforce_admins"), pass("allow_deletions")), repo("a", StatusNonCompliant, pass("enforce_admins"), fail("allow_deletions")), "regressed"},
+		{"same failures", repo("a", StatusNonCompliant, fail("enforce_admins")), repo("a", StatusNonCompliant, fail("enforce_admins")), ""},
+		{"exempted rule isn't fixed or failing", repo("a", StatusCompliant, pass("enforce_admins")), repo("a", StatusCompliant, config.RuleDiff{Rule: "enforce_admins", Exempt: true}), ""},
+		{"compliant to error", repo("a", StatusCompliant), repo("a", StatusError), "regressed"},
+		{"error to compliant", repo("a", StatusError), repo("a", StatusCompliant), "improved"},
+		{"error to non-compliant", repo("a", StatusError), repo("a", StatusNonCompliant, fail("enforce_admins")), "changed"},
+		{"non-compliant to error", repo("a", StatusNonCompliant, fail("enforce_admins")), repo("a", StatusError), "changed"},
+		{"compliant to archived", repo("a", StatusCompliant), repo("a", StatusArchived), "changed"},
+		{"skipped to compliant", repo("a", StatusSkipped), repo("a", StatusCompliant), "changed"},
+		{"still skipped", repo("a", StatusSkipped), repo("a", StatusSkipped), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift := compareAudits([]RepoAuditResult{tt.old}, []RepoAuditResult{tt.new})
+			buckets := map[string][]repoChange{
+				"regressed": drift.Regressed,
+				"improved":  drift.Improved,
+				"added":     drift.Added,
+				"removed":   drift.Removed,
+				"changed":   drift.Changed,
+			}
+			for bucket, changes := range buckets {
+				want := 0
+				if bucket == tt.want {
+					want = 1
+				}
+				if len(changes) != want {
+					t.Errorf("%s = %+v, want %d", bucket, changes, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareAuditsAddedAndRemoved(t *testing.T) {
+	oldResults := []RepoAuditResult{
+		{Owner: "acme", Repo: "api", Status: StatusCompliant},
+		{Owner: "acme", Repo: "gone", Status: StatusNonCompliant},
+	}
+	newResults := []RepoAuditResult{
+		{Owner: "acme", Repo: "api", Status: StatusCompliant},
+		{Owner: "acme", Repo: "web", Status: StatusNonCompliant},
+		// Same name under another owner is a different repo
+		{Owner: "globex", Repo: "gone", Status: StatusCompliant},
+	}
+
+	drift := compareAudits(oldResults, newResults)
+	if len(drift.Added) != 2 || drift.Added[0].Repo != "acme/web" || drift.Added[1].Repo != "globex/gone" {
+		t.Errorf("added = %+v, want acme/web and globex/gone", drift.Added)
+	}
+	if len(drift.Added) == 2 && drift.Added[0].NewStatus != StatusNonCompliant {
+		t.Errorf("added status = %q, want %q", drift.Added[0].NewStatus, StatusNonCompliant)
+	}
+	if len(drift.Removed) != 1 || drift.Removed[0].Repo != "acme/gone" || drift.Removed[0].OldStatus != StatusNonCompliant {
+		t.Errorf("removed = %+v, want acme/gone (was non_compliant)", drift.Removed)
+	}
+	if len(drift.Regressed)+len(drift.Improved)+len(drift.Changed) != 0 {
+		t.Errorf("unchanged repos were reported: %+v", drift)
+	}
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wdm0006/rampart/internal/config"
+	"github.com/wdm0006/rampart/internal/history"
+)
+
+// reportHistoryRuns caps how many runs the HTML trend chart shows
+const reportHistoryRuns = 30
+
+// ReportHistory holds the compliance trend shown in the HTML report
+type ReportHistory struct {
+	Runs              []history.Run
+	Previous          *history.Run
+	NewlyNonCompliant []string
+	Fixed             []string
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show compliance over time from a history file",
+	Long:  `Shows the audit runs recorded with 'rampart audit --history' and which repos changed between the last two runs.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("history")
+		owner, _ := cmd.Flags().GetString("owner")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		if path == "" {
+			exitWithError("--history is required")
+		}
+
+		runs, err := history.Load(path)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		if len(runs) == 0 {
+			exitWithError(fmt.Sprintf("no runs recorded in %s", path))
+		}
+		if owner == "" {
+			owner = runs[len(runs)-1].Owner
+		}
+		runs = history.ForOwner(runs, owner)
+		if len(runs) == 0 {
+			exitWithError(fmt.Sprintf("no runs recorded for %s in %s", owner, path))
+		}
+
+		shown := runs
+		if limit > 0 && len(shown) > limit {
+			shown = shown[len(shown)-limit:]
+		}
+
+		fmt.Printf("Compliance history for %s (%d of %d runs)\n\n", owner, len(shown), len(runs))
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  TIME\tCOMPLIANT\tNON-COMPLIANT\tERRORS\tSKIPPED\tTOTAL\tCOMPLIANCE")
+		for _, r := range shown {
+			fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\t%d\t%d\t%d%%\n",
+				formatRunTime(r.Time), r.Compliant, r.NonCompliant, r.Errors, r.Skipped, r.Total, r.Percent())
+		}
+		tw.Flush()
+
+		if len(runs) > 1 {
+			prev, cur := runs[len(runs)-2], runs[len(runs)-1]
+			fmt.Println()
+			printHistoryChanges(prev, history.Compare(prev, cur))
+		}
+	},
+}
+
+func init() {
+	historyCmd.Flags().String("history", "", "Path to the history file written by 'audit --history'")
+	historyCmd.Flags().String("owner", "", "Owner to show (defaults to the owner of the latest run)")
+	historyCmd.Flags().Int("limit", 20, "Show at most this many recent runs (0 for all)")
+}
+
+// newHistoryRun converts audit results into a history record
+func newHistoryRun(owner, configPath string, results []RepoAuditResult) history.Run {
+	s := summarize(results)
+	// History doesn't track why a repo wasn't checked, so every repo that
+	// wasn't checked counts as skipped
+	run := history.Run{
+		Time:         time.Now().UTC(),
+		Owner:        owner,
+		Config:       configPath,
+		Compliant:    s.Compliant,
+		NonCompliant: s.NonCompliant,
+		Errors:       s.Errors,
+		Skipped:      s.NotChecked(),
+		Total:        s.Total,
+		Repos:        make(map[string]history.Repo, len(results)),
+	}
+	for _, r := range results {
+		repo := history.Repo{Status: r.Status}
+		for _, d := range config.Failing(r.Diffs) {
+			repo.Failing = append(repo.Failing, d.Rule)
+		}
+		run.Repos[r.FullName()] = repo
+	}
+	return run
+}
+
+// recordHistory appends this audit to the history file and returns the
+// owner's trend, including the run just recorded
+func recordHistory(path, owner, configPath string, results []RepoAuditResult) (*ReportHistory, error) {
+	runs, err := history.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	past := history.ForOwner(runs, owner)
+
+	cur := newHistoryRun(owner, configPath, results)
+	if err := history.Append(path, cur); err != nil {
+		return nil, err
+	}
+
+	trend := append(past, cur)
+	if len(trend) > reportHistoryRuns {
+		trend = trend[len(trend)-reportHistoryRuns:]
+	}
+	h := &ReportHistory{Runs: trend}
+	if len(past) > 0 {
+		prev := past[len(past)-1]
+		changes := history.Compare(prev, cur)
+		h.Previous = &prev
+		h.NewlyNonCompliant = changes.NewlyNonCompliant
+		h.Fixed = changes.Fixed
+	}
+	return h, nil
+}
+
+func printHistoryChanges(prev history.Run, changes history.Changes) {
+	fmt.Printf("Since last run (%s): %d newly non-compliant, %d fixed\n",
+		formatRunTime(prev.Time), len(changes.NewlyNonCompliant), len(changes.Fixed))
+	if len(changes.NewlyNonCompliant) > 0 {
+		fmt.Printf("  newly non-compliant: %s\n", strings.Join(changes.NewlyNonCompliant, ", "))
+	}
+	if len(changes.Fixed) > 0 {
+		fmt.Printf("  fixed: %s\n", strings.Join(changes.Fixed, ", "))
+	}
+}
+
+func formatRunTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 MST")
+}
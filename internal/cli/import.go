@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wdm0006/rampart/internal/config"
+	"github.com/wdm0006/rampart/internal/github"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Generate rampart.yaml from an existing repo's branch protection",
+	Long:  `Reads the branch protection of an existing repo and writes a config file that reproduces exactly those rules.`,
+	Example: `  # Use the default branch of myorg/api as the standard
+  rampart import --owner myorg --repo api
+
+  # Import a specific branch into another file
+  rampart import --owner myorg --repo api --branch release --output release.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, _ := cmd.Flags().GetString("owner")
+		repo, _ := cmd.Flags().GetString("repo")
+		branch, _ := cmd.Flags().GetString("branch")
+		path, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+
+		if repo == "" {
+			exitWithError("--repo is required")
+		}
+		if !force {
+			if _, err := os.Stat(path); err == nil {
+				exitWithError(fmt.Sprintf("%s already exists (use --force to overwrite)", path))
+			}
+		}
+
+		if owner == "" {
+			user, err := github.GetCurrentUser()
+			if err != nil {
+				exitWithError(err.Error())
+			}
+			owner = user
+		}
+
+		// Without --branch the config targets each repo's default branch,
+		// so read the protection from this repo's default branch too
+		cfg := config.Config{Branch: "default"}
+		readBranch := branch
+		if branch != "" {
+			cfg.Branch = branch
+		} else {
+			r, err := github.GetRepo(owner, repo)
+			if err != nil {
+				exitWithError(err.Error())
+			}
+			readBranch = r.DefaultBranch
+		}
+
+		rules, _, err := github.GetBranchProtection(owner, repo, readBranch)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+		cfg.Rules = rules
+
+		if err := config.Write(path, cfg); err != nil {
+			exitWithError(err.Error())
+		}
+
+		fmt.Printf("Created %s from the branch protection of %s/%s (branch: %s)\n", path, owner, repo, readBranch)
+		fmt.Println("Review the file, then run: rampart audit --owner <name>")
+	},
+}
+
+func init() {
+	importCmd.Flags().String("owner", "", "GitHub user or org that owns the repo (defaults to authenticated user)")
+	importCmd.Flags().String("repo", "", "Repo to import branch protection from")
+	importCmd.Flags().String("branch", "", "Branch to import (defaults to the repo's default branch)")
+	importCmd.Flags().String("output", "rampart.yaml", "Path to write the config file")
+	importCmd.Flags().Bool("force", false, "Overwrite the output file if it exists")
+}
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wdm0006/rampart/internal/config"
+	"github.com/wdm0006/rampart/internal/github"
+)
+
+// ruleEstimate summarises how a rule is set across an org and how many repos
+// would fail the proposed value
+type ruleEstimate struct {
+	Rule         string
+	Distribution string
+	Proposed     string
+	WouldFail    int
+}
+
+// sampleOrgProtection reads the default-branch protection of every repo the
+// owner has. Repos that can't be read are reported and left out.
+func sampleOrgProtection(owner string) []config.Rules {
+	fmt.Printf("Fetching repos for %s...\n", owner)
+	repos, err := github.ListRepos(owner, github.ListOptions{})
+	if err != nil {
+		exitWithError(err.Error())
+	}
+
+	fmt.Printf("Reading branch protection for %d repos\n", len(repos))
+	var samples []config.Rules
+	for _, r := range repos {
+		rules, _, err := github.GetBranchProtection(owner, r.Name, r.DefaultBranch)
+		if err != nil {
+			fmt.Printf("  - %s (skipped: %s)\n", r.Name, err)
+			continue
+		}
+		samples = append(samples, rules)
+	}
+	return samples
+}
+
+// inferRules proposes the rules most repos already follow: the majority value
+// for each boolean, the median approval count, and the status checks that
+// most repos requiring checks have in common. Settings that only apply under
+// another rule (e.g. approvals under require_pull_request) are taken from the
+// repos where that rule is on.
+func inferRules(samples []config.Rules) (config.Rules, []ruleEstimate) {
+	var withPR, withChecks []config.Rules
+	for _, s := range samples {
+		if s.RequirePullRequest {
+			withPR = append(withPR, s)
+		}
+		if s.RequireStatusChecks {
+			withChecks = append(withChecks, s)
+		}
+	}
+
+	dist := map[string]string{}
+	majority := func(rule string, set []config.Rules, get func(config.Rules) bool) bool {
+		n := 0
+		for _, s := range set {
+			if get(s) {
+				n++
+			}
+		}
+		dist[rule] = percentOf(n, len(set))
+		return len(set) > 0 && n*2 > len(set)
+	}
+
+	proposed := config.Rules{
+		RequirePullRequest:             majority("require_pull_request", samples, func(r config.Rules) bool { return r.RequirePullRequest }),
+		DismissStaleReviews:            majority("dismiss_stale_reviews", withPR, func(r config.Rules) bool { return r.DismissStaleReviews }),
+		RequireCodeOwnerReviews:        majority("require_code_owner_reviews", withPR, func(r config.Rules) bool { return r.RequireCodeOwnerReviews }),
+		RequireStatusChecks:            majority("require_status_checks", samples, func(r config.Rules) bool { return r.RequireStatusChecks }),
+		StrictStatusChecks:             majority("strict_status_checks", withChecks, func(r config.Rules) bool { return r.StrictStatusChecks }),
+		EnforceAdmins:                  majority("enforce_admins", samples, func(r config.Rules) bool { return r.EnforceAdmins }),
+		AllowForcePushes:               majority("allow_force_pushes", samples, func(r config.Rules) bool { return r.AllowForcePushes }),
+		AllowDeletions:                 majority("allow_deletions", samples, func(r config.Rules) bool { return r.AllowDeletions }),
+		RequiredLinearHistory:          majority("required_linear_history", samples, func(r config.Rules) bool { return r.RequiredLinearHistory }),
+		RequiredConversationResolution: majority("required_conversation_resolution", samples, func(r config.Rules) bool { return r.RequiredConversationResolution }),
+		RequiredChecks:                 []string{},
+	}
+
+	// Approvals: median across repos that require PRs
+	approvals := make([]int, len(withPR))
+	counts := map[int]int{}
+	for i, s := range withPR {
+		approvals[i] = s.RequiredApprovals
+		counts[s.RequiredApprovals]++
+	}
+	sort.Ints(approvals)
+	if len(approvals) > 0 {
+		proposed.RequiredApprovals = approvals[(len(approvals)-1)/2]
+		var parts []string
+		for _, n := range uniqueInts(approvals) {
+			parts = append(parts, fmt.Sprintf("%d×%d", n, counts[n]))
+		}
+		dist["required_approvals"] = fmt.Sprintf("median %d (%s)", proposed.RequiredApprovals, strings.Join(parts, ", "))
+	} else {
+		dist["required_approvals"] = "n/a"
+	}
+
+	// Checks: those required by a majority of repos that require checks
+	checkCounts := map[string]int{}
+	for _, s := range withChecks {
+		for _, c := range s.RequiredChecks {
+			checkCounts[c]++
+		}
+	}
+	for c, n := range checkCounts {
+		if proposed.RequireStatusChecks && n*2 > len(withChecks) {
+			proposed.RequiredChecks = append(proposed.RequiredChecks, c)
+		}
+	}
+	sort.Strings(proposed.RequiredChecks)
+	dist["required_checks"] = fmt.Sprintf("%d distinct", len(checkCounts))
+
+	// Settings that only apply under a rule that isn't proposed stay off
+	if !proposed.RequirePullRequest {
+		proposed.RequiredApprovals = 0
+		proposed.DismissStaleReviews = false
+		proposed.RequireCodeOwnerReviews = false
+	}
+	if !proposed.RequireStatusChecks {
+		proposed.StrictStatusChecks = false
+	}
+
+	// Impact: how many repos each rule would fail under the proposal
+	wouldFail := map[string]int{}
+	proposedValue := map[string]string{}
+	for _, s := range samples {
+		for _, d := range config.Compare(proposed, s) {
+			proposedValue[d.Rule] = d.Want
+			if !d.Pass {
+				wouldFail[d.Rule]++
+			}
+		}
+	}
+
+	var estimates []ruleEstimate
+	for _, rule := range config.RuleNames {
+		want, compared := proposedValue[rule]
+		if !compared {
+			want = "(not checked)"
+		}
+		estimates = append(estimates, ruleEstimate{
+			Rule:         rule,
+			Distribution: dist[rule],
+			Proposed:     want,
+			WouldFail:    wouldFail[rule],
+		})
+	}
+	return proposed, estimates
+}
+
+func percentOf(n, total int) string {
+	if total == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%d%% true (%d/%d)", n*100/total, n, total)
+}
+
+func uniqueInts(sorted []int) []int {
+	var out []int
+	for i, n := range sorted {
+		if i == 0 || n != sorted[i-1] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+func TestInferRules(t *testing.T) {
+	pr := func(approvals int, dismissStale bool) config.Rules {
+		return config.Rules{RequirePullRequest: true, RequiredApprovals: approvals, DismissStaleReviews: dismissStale, RequiredChecks: []string{}}
+	}
+	checks := func(strict bool, names ...string) config.Rules {
+		return config.Rules{RequireStatusChecks: true, StrictStatusChecks: strict, RequiredChecks: names}
+	}
+	unprotected := config.Rules{RequiredChecks: []string{}}
+	none := config.Rules{RequiredChecks: []string{}}
+
+	tests := []struct {
+		name    string
+		samples []config.Rules
+		want    config.Rules
+	}{
+		{"no repos", nil, none},
+		{"no protection anywhere", []config.Rules{unprotected, unprotected, unprotected}, none},
+		{
+			"mixed protected and unprotected",
+			[]config.Rules{pr(1, true), pr(2, true), pr(2, false), unprotected, unprotected},
+			config.Rules{RequirePullRequest: true, RequiredApprovals: 2, DismissStaleReviews: true, RequiredChecks: []string{}},
+		},
+		{
+			// Approvals and stale reviews only count where PRs are required,
+			// and are dropped when PRs aren't proposed
+			"pull requests in a minority",
+			[]config.Rules{pr(3, true), unprotected, unprotected},
+			none,
+		},
+		{
+			"lower median of an even count",
+			[]config.Rules{pr(1, false), pr(3, false)},
+			config.Rules{RequirePullRequest: true, RequiredApprovals: 1, RequiredChecks: []string{}},
+		},
+		{
+			"checks most repos with checks share",
+			[]config.Rules{checks(true, "ci/lint", "ci/build"), checks(true, "ci/build"), checks(false, "ci/build", "ci/e2e"), unprotected},
+			config.Rules{RequireStatusChecks: true, StrictStatusChecks: true, RequiredChecks: []string{"ci/build"}},
+		},
+		{
+			"a tie isn't a majority",
+			[]config.Rules{{EnforceAdmins: true, RequiredChecks: []string{}}, unprotected},
+			none,
+		},
+		{
+			"allow rules follow the majority too",
+			[]config.Rules{{AllowForcePushes: true, RequiredChecks: []string{}}, {AllowForcePushes: true, AllowDeletions: true, RequiredChecks: []string{}}, unprotected},
+			config.Rules{AllowForcePushes: true, RequiredChecks: []string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, estimates := inferRules(tt.samples)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("inferRules = %+v, want %+v", got, tt.want)
+			}
+			if len(estimates) != len(config.RuleNames) {
+				t.Errorf("got %d estimates, want one per rule (%d)", len(estimates), len(config.RuleNames))
+			}
+		})
+	}
+}
+
+func TestInferRulesEstimates(t *testing.T) {
+	samples := []config.Rules{
+		{RequirePullRequest: true, RequiredApprovals: 1, RequiredChecks: []string{}},
+		{RequirePullRequest: true, RequiredApprovals: 2, RequiredChecks: []string{}},
+		{RequirePullRequest: true, RequiredApprovals: 2, RequiredChecks: []string{}},
+		{RequiredChecks: []string{}},
+	}
+	// The unprotected repo fails both rules, and the one-approval repo fails
+	// the proposed two
+	_, estimates := inferRules(samples)
+
+	byRule := make(map[string]ruleEstimate, len(estimates))
+	for _, e := range estimates {
+		byRule[e.Rule] = e
+	}
+	want := map[string]ruleEstimate{
+		"require_pull_request": {Rule: "require_pull_request", Distribution: "75% true (3/4)", Proposed: "true", WouldFail: 1},
+		"required_approvals":   {Rule: "required_approvals", Distribution: "median 2 (1×1, 2×2)", Proposed: "2", WouldFail: 2},
+	}
+	for rule, w := range want {
+		if got := byRule[rule]; got != w {
+			t.Errorf("%s estimate = %+v, want %+v", rule, got, w)
+		}
+	}
+}
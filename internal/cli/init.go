@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"os"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"github.com/wdm0006/rampart/internal/config"
@@ -11,8 +12,12 @@ import (
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Generate a default rampart.yaml config file",
-	Long:  `Creates a rampart.yaml file in the current directory with sensible default branch protection rules.`,
+	Long: `Creates a rampart.yaml file in the current directory with sensible default branch protection rules.
+
+With --from-org, proposes rules based on what the owner's repos already do instead, and
+estimates how many repos each proposed rule would fail.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		fromOrg, _ := cmd.Flags().GetString("from-org")
 		path := "rampart.yaml"
 
 		// Check if file already exists
@@ -20,11 +25,39 @@ var initCmd = &cobra.Command{
 			exitWithError(fmt.Sprintf("%s already exists", path))
 		}
 
-		if err := config.WriteDefault(path); err != nil {
+		if fromOrg == "" {
+			if err := config.WriteDefault(path); err != nil {
+				exitWithError(err.Error())
+			}
+
+			fmt.Printf("Created %s with default branch protection rules\n", path)
+			fmt.Println("Edit the file to customize rules, then run: rampart audit --owner <name>")
+			return
+		}
+
+		samples := sampleOrgProtection(fromOrg)
+		if len(samples) == 0 {
+			exitWithError(fmt.Sprintf("no readable branch protection found for %s", fromOrg))
+		}
+
+		rules, estimates := inferRules(samples)
+		if err := config.Write(path, config.Config{Branch: "default", Rules: rules}); err != nil {
 			exitWithError(err.Error())
 		}
 
-		fmt.Printf("Created %s with default branch protection rules\n", path)
-		fmt.Println("Edit the file to customize rules, then run: rampart audit --owner <name>")
+		fmt.Printf("\nProposed rules from %d repos:\n\n", len(samples))
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  RULE\tCURRENT\tPROPOSED\tWOULD FAIL")
+		for _, e := range estimates {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%d\n", e.Rule, e.Distribution, e.Proposed, e.WouldFail)
+		}
+		tw.Flush()
+
+		fmt.Printf("\nCreated %s with the proposed rules\n", path)
+		fmt.Printf("Adjust the file, then run: rampart audit --owner %s\n", fromOrg)
 	},
 }
+
+func init() {
+	initCmd.Flags().String("from-org", "", "Propose rules based on the existing protection of this user's or org's repos")
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitRepoCase names the single testcase used for repos that weren't
+// compared rule by rule (skipped or errored)
+const junitRepoCase = "branch_protection"
+
+// writeJUnit writes audit results as JUnit XML: one testsuite per repo and
+// one testcase per compared rule
+func writeJUnit(w io.Writer, results []RepoAuditResult) error {
+	doc := junitTestSuites{Name: "rampart"}
+
+	for _, r := range results {
+		classname := r.FullName()
+		suite := junitTestSuite{Name: classname}
+
+		switch {
+		case r.NotChecked():
+			suite.Cases = []junitTestCase{{
+				Name:      junitRepoCase,
+				Classname: classname,
+				Skipped:   &junitSkipped{Message: r.SkipReason},
+			}}
+			suite.Skipped = 1
+		case r.Status == StatusError:
+			suite.Cases = []junitTestCase{{
+				Name:      junitRepoCase,
+				Classname: classname,
+				Error:     &junitProblem{Message: r.Error, Text: r.Error},
+			}}
+			suite.Errors = 1
+		default:
+			for _, d := range r.Diffs {
+				tc := junitTestCase{Name: d.Rule, Classname: classname}
+				if d.Exempt {
+					tc.Skipped = &junitSkipped{Message: "exempt"}
+					suite.Skipped++
+				} else if !d.Pass {
+					msg := fmt.Sprintf("want %s, got %s", d.Want, d.Got)
+					tc.Failure = &junitProblem{
+						Message: msg,
+						Text:    fmt.Sprintf("%s on branch %s: %s", d.Rule, r.Branch, msg),
+					}
+					suite.Failures++
+				}
+				suite.Cases = append(suite.Cases, tc)
+			}
+		}
+
+		suite.Tests = len(suite.Cases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Errors += suite.Errors
+		doc.Skipped += suite.Skipped
+		doc.Suites = append(doc.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write JUnit XML: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write JUnit XML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
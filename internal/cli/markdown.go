@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+// writeMarkdown writes a compliance summary as GitHub-flavored Markdown:
+// counts (per owner too when several were audited), then a table of
+// non-compliant repos with their failing rules
+func writeMarkdown(w io.Writer, configPath string, run auditRun) error {
+	results := run.Results
+	s := summarize(results)
+	multiOwner := len(run.Owners) > 1
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Rampart compliance: %s\n\n", strings.Join(run.Owners, ", "))
+	fmt.Fprintf(&b, "Config `%s`, branch `%s`\n\n", configPath, run.Config.Branch)
+	if multiOwner {
+		b.WriteString("| Owner | Compliant | Non-compliant | Errors | Not checked | Total | Score |\n")
+		b.WriteString("|---|---:|---:|---:|---:|---:|---:|\n")
+		for _, o := range summarizeOwners(run) {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d | %s |\n", markdownCell(o.Owner),
+				o.Summary.Compliant, o.Summary.NonCompliant, o.Summary.Errors, o.Summary.NotChecked(), o.Summary.Total, formatScore(o.Summary.Score))
+		}
+		fmt.Fprintf(&b, "| **Total** | %d | %d | %d | %d | %d | %s |\n",
+			s.Compliant, s.NonCompliant, s.Errors, s.NotChecked(), s.Total, formatScore(s.Score))
+	} else {
+		b.WriteString("| Compliant | Non-compliant | Errors | Not checked | Total | Score |\n")
+		b.WriteString("|---:|---:|---:|---:|---:|---:|\n")
+		fmt.Fprintf(&b, "| %d | %d | %d | %d | %d | %s |\n",
+			s.Compliant, s.NonCompliant, s.Errors, s.NotChecked(), s.Total, formatScore(s.Score))
+	}
+
+	if len(run.SkippedOwners) > 0 {
+		b.WriteString("\n### Skipped owners\n\n")
+		b.WriteString("| Owner | Reason |\n")
+		b.WriteString("|---|---|\n")
+		for _, o := range run.SkippedOwners {
+			fmt.Fprintf(&b, "| %s | %s |\n", markdownCell(o.Owner), markdownCell(o.Reason))
+		}
+	}
+
+	if s.NonCompliant > 0 {
+		b.WriteString("\n### Non-compliant repos\n\n")
+		b.WriteString("| Repo | Branch | Score | Failing rules |\n")
+		b.WriteString("|---|---|---:|---|\n")
+		for _, r := range results {
+			if r.Status != StatusNonCompliant {
+				continue
+			}
+			var rules []string
+			for _, d := range config.Failing(r.Diffs) {
+				rules = append(rules, fmt.Sprintf("`%s` %s (want %s, got %s)", d.Rule, d.Severity, d.Want, d.Got))
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+				markdownCell(repoLabel(r, multiOwner)), markdownCell(r.Branch), formatScore(r.Score), markdownCell(strings.Join(rules, "<br>")))
+		}
+	}
+
+	if s.NotChecked() > 0 {
+		b.WriteString("\n### Not checked\n\n")
+		b.WriteString("| Repo | Status | Reason |\n")
+		b.WriteString("|---|---|---|\n")
+		for _, r := range results {
+			if r.NotChecked() {
+				fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(repoLabel(r, multiOwner)), r.Status, markdownCell(r.SkipReason))
+			}
+		}
+	}
+
+	if s.Errors > 0 {
+		b.WriteString("\n### Errors\n\n")
+		b.WriteString("| Repo | Error |\n")
+		b.WriteString("|---|---|\n")
+		for _, r := range results {
+			if r.Status == StatusError {
+				fmt.Fprintf(&b, "| %s | %s |\n", markdownCell(repoLabel(r, multiOwner)), markdownCell(r.Error))
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write Markdown: %w", err)
+	}
+	return nil
+}
+
+// markdownCell makes a value safe to place inside a table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", " ")
+}
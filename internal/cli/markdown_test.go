@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+func TestWriteMarkdownCountsAddUp(t *testing.T) {
+	results := []RepoAuditResult{
+		{Owner: "acme", Repo: "api", Status: StatusCompliant},
+		{Owner: "acme", Repo: "old", Status: StatusArchived, SkipReason: "read-only"},
+		{Owner: "acme", Repo: "empty", Status: StatusNoBranch, SkipReason: "repository is empty"},
+		{Owner: "globex", Repo: "stale", Status: StatusInactive, SkipReason: "created 2019-01-01, before 2021-01-01"},
+		{Owner: "globex", Repo: "web", Status: StatusSkipped, SkipReason: "excluded"},
+	}
+
+	for name, owners := range map[string][]string{
+		"single owner": {"acme"},
+		"owners":       {"acme", "globex"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			run := auditRun{Config: config.Default(), Owners: owners}
+			for _, r := range results {
+				for _, o := range owners {
+					if r.Owner == o {
+						run.Results = append(run.Results, r)
+					}
+				}
+			}
+
+			var b bytes.Buffer
+			if err := writeMarkdown(&b, "rampart.yaml", run); err != nil {
+				t.Fatal(err)
+			}
+
+			rows := 0
+			for _, line := range strings.Split(b.String(), "\n") {
+				cells := strings.Split(strings.Trim(line, "| "), " | ")
+				// Count rows end with four counts, the total and the score
+				if len(cells) < 6 {
+					continue
+				}
+				total, err := strconv.Atoi(cells[len(cells)-2])
+				if err != nil {
+					continue
+				}
+				sum := 0
+				for _, c := range cells[len(cells)-6 : len(cells)-2] {
+					n, err := strconv.Atoi(c)
+					if err != nil {
+						t.Fatalf("non-numeric count %q in %q", c, line)
+					}
+					sum += n
+				}
+				if sum != total {
+					t.Errorf("counts in %q add up to %d, want the total %d", line, sum, total)
+				}
+				rows++
+			}
+			if rows == 0 {
+				t.Fatalf("no count rows in:\n%s", b.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// Output formats accepted by --format
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatSARIF    = "sarif"
+	formatJUnit    = "junit"
+	formatMarkdown = "markdown"
+	formatCSV      = "csv"
+)
+
+// jsonSchemaVersion is bumped whenever a field is removed or changes meaning.
+// Adding fields does not change the version.
+const jsonSchemaVersion = 1
+
+// statusOut receives progress messages. It is switched to stderr for
+// machine-readable formats so stdout only carries the formatted output.
+var statusOut io.Writer = os.Stdout
+
+// setOutputFormat validates format against the allowed list and routes
+// progress output accordingly
+func setOutputFormat(format string, allowed ...string) {
+	for _, f := range allowed {
+		if f == format {
+			if format != formatText {
+				statusOut = os.Stderr
+			}
+			return
+		}
+	}
+	exitWithError(fmt.Sprintf("unknown format %q (valid: %v)", format, allowed))
+}
+
+// auditSummary counts repos by audit status
+type auditSummary struct {
+	Compliant    int `json:"compliant"`
+	NonCompliant int `json:"non_compliant"`
+	Errors       int `json:"errors"`
+	Skipped      int `json:"skipped"`
+	Archived     int `json:"archived"`
+	NoBranch     int `json:"no_branch"`
+	Inactive     int `json:"inactive"`
+	Total        int `json:"total"`
+	// Score is the mean compliance score of the repos that were compared
+	Score *float64 `json:"score,omitempty"`
+}
+
+// NotChecked counts the repos that were listed but deliberately not compared
+func (s auditSummary) NotChecked() int {
+	return s.Skipped + s.Archived + s.NoBranch + s.Inactive
+}
+
+func summarize(results []RepoAuditResult) auditSummary {
+	s := auditSummary{Total: len(results)}
+	var scoreSum float64
+	scored := 0
+	for _, r := range results {
+		if r.Score != nil {
+			scoreSum += *r.Score
+			scored++
+		}
+		switch r.Status {
+		case StatusCompliant:
+			s.Compliant++
+		case StatusNonCompliant:
+			s.NonCompliant++
+		case StatusError:
+			s.Errors++
+		case StatusSkipped:
+			s.Skipped++
+		case StatusArchived:
+			s.Archived++
+		case StatusNoBranch:
+			s.NoBranch++
+		case StatusInactive:
+			s.Inactive++
+		}
+	}
+	if scored > 0 {
+		score := math.Round(scoreSum/float64(scored)*10) / 10
+		s.Score = &score
+	}
+	return s
+}
+
+// ownerSummary counts one owner's repos
+type ownerSummary struct {
+	Owner   string       `json:"owner"`
+	Summary auditSummary `json:"summary"`
+}
+
+// summarizeOwners counts each audited owner's repos, in the order the owners
+// were given
+func summarizeOwners(run auditRun) []ownerSummary {
+	out := make([]ownerSummary, 0, len(run.Owners))
+	for _, owner := range run.Owners {
+		var results []RepoAuditResult
+		for _, r := range run.Results {
+			if r.Owner == owner {
+				results = append(results, r)
+			}
+		}
+		out = append(out, ownerSummary{Owner: owner, Summary: summarize(results)})
+	}
+	return out
+}
+
+// spansOwners reports whether results come from more than one owner
+func spansOwners(results []RepoAuditResult) bool {
+	for _, r := range results {
+		if r.Owner != results[0].Owner {
+			return true
+		}
+	}
+	return false
+}
+
+// repoLabel names a repo in tabular output: the bare name when every repo has
+// the same owner, owner/name otherwise
+func repoLabel(r RepoAuditResult, multiOwner bool) string {
+	if multiOwner {
+		return r.FullName()
+	}
+	return r.Repo
+}
+
+// auditJSON is the document written by `audit --format json`. Owner joins
+// the audited owners with commas; Owners breaks the summary down per owner.
+type auditJSON struct {
+	SchemaVersion int               `json:"schema_version"`
+	Owner         string            `json:"owner"`
+	Config        string            `json:"config"`
+	Branch        string            `json:"branch"`
+	GeneratedAt   string            `json:"generated_at"`
+	Summary       auditSummary      `json:"summary"`
+	Owners        []ownerSummary    `json:"owners"`
+	SkippedOwners []ownerSkip       `json:"skipped_owners,omitempty"`
+	Results       []RepoAuditResult `json:"results"`
+}
+
+func newAuditJSON(configPath string, run auditRun) auditJSON {
+	results := run.Results
+	if results == nil {
+		results = []RepoAuditResult{}
+	}
+	return auditJSON{
+		SchemaVersion: jsonSchemaVersion,
+		Owner:         run.OwnerLabel(),
+		Config:        configPath,
+		Branch:        run.Config.Branch,
+		GeneratedAt:   nowRFC3339(),
+		Summary:       summarize(results),
+		Owners:        summarizeOwners(run),
+		SkippedOwners: run.SkippedOwners,
+		Results:       results,
+	}
+}
+
+// applySummary counts repos by apply outcome
+type applySummary struct {
+	Updated           int `json:"updated"`
+	StillNonCompliant int `json:"still_non_compliant"`
+	Failed            int `json:"failed"`
+	WouldUpdate       int `json:"would_update"`
+	Skipped           int `json:"skipped"`
+}
+
+// applyJSON is the document written by `apply --format json`
+type applyJSON struct {
+	SchemaVersion int           `json:"schema_version"`
+	Owner         string        `json:"owner"`
+	Config        string        `json:"config"`
+	Branch        string        `json:"branch"`
+	GeneratedAt   string        `json:"generated_at"`
+	DryRun        bool          `json:"dry_run"`
+	Summary       applySummary  `json:"summary"`
+	Results       []ApplyResult `json:"results"`
+}
+
+func nowRFC3339() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
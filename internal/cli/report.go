@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"html/template"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/wdm0006/rampart/internal/config"
 )
 
 // ReportData holds all data passed to the HTML report template.
@@ -14,10 +17,88 @@ type ReportData struct {
 	Branch       string
 	GeneratedAt  string
 	Results      []RepoAuditResult
+	Rules        []RuleStat
+	History      *ReportHistory
+	Exemptions   []config.Exemption
 	Compliant    int
 	NonCompliant int
 	Skipped      int
+	Archived     int
+	NoBranch     int
+	Inactive     int
 	Total        int
+	Score        string
+	// Owners breaks the totals down per owner; empty for single-owner audits
+	Owners        []ownerSummary
+	SkippedOwners []ownerSkip
+	// Policies lists the policy names used by at least one repo
+	Policies []string
+}
+
+// RuleStat counts how many audited repos failed a rule
+type RuleStat struct {
+	Rule     string
+	Failures int
+	Compared int
+}
+
+// Percent returns the share of compared repos failing the rule, for the bar width
+func (s RuleStat) Percent() int {
+	if s.Compared == 0 {
+		return 0
+	}
+	return s.Failures * 100 / s.Compared
+}
+
+// reportCell is one square of the rule-by-repo heatmap
+type reportCell struct {
+	State string
+	Title string
+}
+
+var reportFuncs = template.FuncMap{
+	"score": formatScore,
+	"add":   func(a, b int) int { return a + b },
+	// badge labels a repo that wasn't checked
+	"badge": func(status string) string {
+		return strings.ToUpper(strings.ReplaceAll(status, "_", " "))
+	},
+	// failing returns a repo's failing rule names, space separated, for filtering
+	"failing": func(r RepoAuditResult) string {
+		var names []string
+		for _, d := range config.Failing(r.Diffs) {
+			names = append(names, d.Rule)
+		}
+		return strings.Join(names, " ")
+	},
+	// cell returns the heatmap state of a rule for a repo
+	"cell": func(r RepoAuditResult, rule string) reportCell {
+		for _, d := range r.Diffs {
+			if d.Rule != rule {
+				continue
+			}
+			if d.Exempt {
+				return reportCell{State: "exempt", Title: fmt.Sprintf("%s: exempt (got %s)", rule, d.Got)}
+			}
+			if d.Pass {
+				return reportCell{State: "pass", Title: fmt.Sprintf("%s: %s", rule, d.Got)}
+			}
+			return reportCell{State: "fail", Title: fmt.Sprintf("%s: want %s, got %s", rule, d.Want, d.Got)}
+		}
+		return reportCell{State: "na", Title: fmt.Sprintf("%s: not compared", rule)}
+	},
+	// hasSources reports whether a repo's diffs record where each rule came from
+	"hasSources": func(r RepoAuditResult) bool {
+		return len(r.Diffs) > 0 && r.Diffs[0].Source != ""
+	},
+	// daysLeft returns the days before an exemption expires
+	"daysLeft": func(e config.Exemption) int {
+		return e.DaysRemaining(time.Now())
+	},
+	// audited reports whether a repo has rule results to show in the heatmap
+	"audited": func(r RepoAuditResult) bool {
+		return r.Status == StatusCompliant || r.Status == StatusNonCompliant
+	},
 }
 
 const reportTemplate = `<!DOCTYPE html>
@@ -33,6 +114,7 @@ const reportTemplate = `<!DOCTYPE html>
     background: #f6f8fa; color: #24292f; margin: 0; padding: 2rem;
   }
   h1 { margin: 0 0 0.25rem; }
+  h2 { font-size: 1.2rem; margin: 2rem 0 0.75rem; }
   .meta { color: #57606a; font-size: 0.9rem; margin-bottom: 1.5rem; }
   .summary {
     display: flex; gap: 1rem; flex-wrap: wrap; margin-bottom: 2rem;
@@ -47,6 +129,41 @@ const reportTemplate = `<!DOCTYPE html>
   .stat.non-compliant .num { color: #cf222e; }
   .stat.skipped .num { color: #6e7781; }
   .stat.total .num { color: #0969da; }
+  .stat.score .num { color: #8250df; }
+  .owners { display: grid; grid-template-columns: repeat(auto-fill, minmax(220px, 1fr)); gap: 1rem; margin-bottom: 2rem; }
+  .owner-card { background: #fff; border: 1px solid #d0d7de; border-radius: 6px; padding: 1rem; cursor: pointer; }
+  .owner-card .name { font-weight: 600; margin-bottom: 0.5rem; }
+  .owner-card .counts { font-size: 0.85rem; color: #57606a; }
+  .panel {
+    background: #fff; border: 1px solid #d0d7de; border-radius: 8px;
+    padding: 1rem; overflow-x: auto;
+  }
+  .toolbar {
+    position: sticky; top: 0; z-index: 1; background: #f6f8fa;
+    display: flex; gap: 0.75rem; flex-wrap: wrap; align-items: center; padding: 0.75rem 0;
+  }
+  .toolbar input, .toolbar select, .toolbar button {
+    font: inherit; font-size: 0.9rem; padding: 0.35rem 0.6rem;
+    border: 1px solid #d0d7de; border-radius: 6px; background: #fff;
+  }
+  .toolbar input[type=search] { min-width: 240px; }
+  .toolbar button { cursor: pointer; }
+  .toolbar .count { color: #57606a; font-size: 0.85rem; margin-left: auto; }
+  .bar { background: #eaeef2; border-radius: 4px; height: 0.6rem; min-width: 120px; }
+  .bar span { display: block; height: 100%; border-radius: 4px; background: #cf222e; }
+  tr.rule-stat { cursor: pointer; }
+  tr.rule-stat:hover td { background: #f6f8fa; }
+  .heatmap td, .heatmap th { padding: 0; border: none; }
+  .heatmap th.rule {
+    writing-mode: vertical-rl; transform: rotate(180deg);
+    font-weight: 500; font-size: 0.75rem; padding: 0.3rem 0; height: 12rem; text-align: left;
+  }
+  .heatmap td.repo { padding: 0.15rem 0.6rem 0.15rem 0; white-space: nowrap; font-size: 0.85rem; }
+  .heatmap td.hm { width: 1.4rem; height: 1.4rem; border: 2px solid #fff; border-radius: 3px; }
+  .hm.pass { background: #2da44e; }
+  .hm.fail { background: #cf222e; }
+  .hm.na { background: #eaeef2; }
+  .hm.exempt { background: #bf8700; }
   .card {
     background: #fff; border: 1px solid #d0d7de; border-radius: 8px;
     margin-bottom: 1rem; border-left: 4px solid #d0d7de; overflow: hidden;
@@ -56,7 +173,7 @@ const reportTemplate = `<!DOCTYPE html>
   .card.skip { border-left-color: #6e7781; }
   .card-header {
     padding: 0.75rem 1rem; font-weight: 600; font-size: 1rem;
-    display: flex; align-items: center; gap: 0.5rem;
+    display: flex; align-items: center; gap: 0.5rem; cursor: pointer;
   }
   .badge {
     font-size: 0.75rem; font-weight: 600; padding: 0.15rem 0.5rem;
@@ -72,6 +189,21 @@ const reportTemplate = `<!DOCTYPE html>
   tr.rule-pass td:first-child::before { content: "✓ "; color: #1a7f37; }
   tr.rule-fail td:first-child::before { content: "✗ "; color: #cf222e; }
   tr.rule-fail { background: #fff5f5; }
+  tr.rule-exempt td:first-child::before { content: "~ "; color: #bf8700; }
+  .trend { display: flex; align-items: flex-end; gap: 4px; height: 8rem; }
+  .trend .run {
+    flex: 1; max-width: 2.5rem; background: #eaeef2; border-radius: 3px 3px 0 0;
+    height: 100%; display: flex; align-items: flex-end;
+  }
+  .trend .run span { display: block; width: 100%; background: #2da44e; border-radius: 3px 3px 0 0; }
+  .changes { display: flex; gap: 2rem; flex-wrap: wrap; margin-top: 1rem; font-size: 0.9rem; }
+  .changes ul { margin: 0.25rem 0 0; padding-left: 1.25rem; }
+  .sev { font-size: 0.75rem; font-weight: 600; text-transform: uppercase; }
+  .sev.critical { color: #82071e; }
+  .sev.high { color: #cf222e; }
+  .sev.medium { color: #9a6700; }
+  .sev.low { color: #57606a; }
+  .hidden { display: none !important; }
   footer {
     margin-top: 2rem; text-align: center; color: #6e7781; font-size: 0.8rem;
   }
@@ -86,42 +218,230 @@ const reportTemplate = `<!DOCTYPE html>
   <div class="stat compliant"><div class="num">{{.Compliant}}</div><div class="label">Compliant</div></div>
   <div class="stat non-compliant"><div class="num">{{.NonCompliant}}</div><div class="label">Non-Compliant</div></div>
   <div class="stat skipped"><div class="num">{{.Skipped}}</div><div class="label">Skipped</div></div>
+  {{if .Archived}}<div class="stat skipped"><div class="num">{{.Archived}}</div><div class="label">Archived</div></div>{{end}}
+  {{if .NoBranch}}<div class="stat skipped"><div class="num">{{.NoBranch}}</div><div class="label">No branch</div></div>{{end}}
+  {{if .Inactive}}<div class="stat skipped"><div class="num">{{.Inactive}}</div><div class="label">Inactive</div></div>{{end}}
+  <div class="stat score"><div class="num">{{.Score}}</div><div class="label">Score</div></div>
+</div>
+
+{{if .Owners}}
+<h2>By owner</h2>
+<div class="owners">
+  {{range .Owners}}
+  <div class="owner-card" data-owner="{{.Owner}}" title="Show repos owned by {{.Owner}}">
+    <div class="name">{{.Owner}}</div>
+    {{with .Summary}}<div class="counts">{{.Total}} repos · {{.Compliant}} compliant · {{add .NonCompliant .Errors}} non-compliant · {{.Skipped}} skipped · score {{score .Score}}</div>{{end}}
+  </div>
+  {{end}}
+</div>
+{{end}}
+
+{{if .SkippedOwners}}
+<h2>Skipped owners</h2>
+<div class="panel">
+  <table>
+    <tr><th>Owner</th><th>Reason</th></tr>
+    {{range .SkippedOwners}}<tr><td>{{.Owner}}</td><td>{{.Reason}}</td></tr>
+    {{end}}
+  </table>
+</div>
+{{end}}
+
+{{with .History}}
+<h2>Compliance over time</h2>
+<div class="panel">
+  <div class="trend">
+    {{range .Runs}}<div class="run" title="{{.Time.Format "2006-01-02 15:04 MST"}}: {{.Percent}}% compliant ({{.Compliant}} of {{.Total}})"><span style="height: {{.Percent}}%"></span></div>
+    {{end}}
+  </div>
+  {{if .Previous}}
+  <div class="changes">
+    <div>
+      <strong>Newly non-compliant since {{.Previous.Time.Format "2006-01-02 15:04 MST"}}</strong>
+      {{if .NewlyNonCompliant}}<ul>{{range .NewlyNonCompliant}}<li>{{.}}</li>{{end}}</ul>{{else}}<div>None</div>{{end}}
+    </div>
+    <div>
+      <strong>Fixed since {{.Previous.Time.Format "2006-01-02 15:04 MST"}}</strong>
+      {{if .Fixed}}<ul>{{range .Fixed}}<li>{{.}}</li>{{end}}</ul>{{else}}<div>None</div>{{end}}
+    </div>
+  </div>
+  {{else}}
+  <p class="meta">This is the first recorded run.</p>
+  {{end}}
+</div>
+{{end}}
+
+{{if .Exemptions}}
+<h2>Active exemptions</h2>
+<div class="panel">
+  <table>
+    <tr><th>Repo</th><th>Rule</th><th>Reason</th><th>Approver</th><th>Expires</th><th>Days left</th></tr>
+    {{range .Exemptions}}
+    <tr>
+      <td>{{.Repo}}</td><td>{{if .Rule}}{{.Rule}}{{else}}all rules{{end}}</td><td>{{.Reason}}</td>
+      <td>{{.Approver}}</td><td>{{.Expires}}</td><td>{{daysLeft .}}</td>
+    </tr>
+    {{end}}
+  </table>
+</div>
+{{end}}
+
+<div class="toolbar">
+  <input type="search" id="search" placeholder="Search repos…" aria-label="Search repos">
+  {{if .Owners}}<select id="owner" aria-label="Filter by owner">
+    <option value="">All owners</option>
+    {{range .Owners}}<option value="{{.Owner}}">{{.Owner}}</option>
+    {{end}}
+  </select>{{end}}
+  <select id="status" aria-label="Filter by status">
+    <option value="">All statuses</option>
+    <option value="compliant">Compliant</option>
+    <option value="non_compliant">Non-compliant</option>
+    <option value="error">Error</option>
+    <option value="skipped">Skipped</option>
+    {{if .Archived}}<option value="archived">Archived</option>{{end}}
+    {{if .NoBranch}}<option value="no_branch">No branch</option>{{end}}
+    {{if .Inactive}}<option value="inactive">Inactive</option>{{end}}
+  </select>
+  {{if .Policies}}<select id="policy" aria-label="Filter by policy">
+    <option value="">All policies</option>
+    <option value="-">Base rules</option>
+    {{range .Policies}}<option value="{{.}}">{{.}}</option>
+    {{end}}
+  </select>{{end}}
+  <select id="rule" aria-label="Filter by failing rule">
+    <option value="">Any rule</option>
+    {{range .Rules}}<option value="{{.Rule}}">Failing {{.Rule}}</option>
+    {{end}}
+  </select>
+  <button type="button" id="expand">Expand all</button>
+  <button type="button" id="collapse">Collapse all</button>
+  <span class="count" id="count"></span>
 </div>
 
+<h2>Failures by rule</h2>
+<div class="panel">
+  <table>
+    <tr><th>Rule</th><th>Failing</th><th>Compared</th><th></th></tr>
+    {{range .Rules}}
+    <tr class="rule-stat" data-rule="{{.Rule}}" title="Show repos failing {{.Rule}}">
+      <td>{{.Rule}}</td><td>{{.Failures}}</td><td>{{.Compared}}</td>
+      <td><div class="bar"><span style="width: {{.Percent}}%"></span></div></td>
+    </tr>
+    {{end}}
+  </table>
+</div>
+
+<h2>Rule heatmap</h2>
+<div class="panel">
+  <table class="heatmap">
+    <tr><th></th>{{range .Rules}}<th class="rule">{{.Rule}}</th>{{end}}</tr>
+    {{$rules := .Rules}}
+    {{range .Results}}{{if audited .}}
+    <tr class="item" data-repo="{{.FullName}}" data-owner="{{.Owner}}" data-policy="{{.Policy}}" data-status="{{.Status}}" data-failing="{{failing .}}">
+      <td class="repo">{{if $.Owners}}{{.FullName}}{{else}}{{.Repo}}{{end}}</td>
+      {{$r := .}}{{range $rules}}{{with cell $r .Rule}}<td class="hm {{.State}}" title="{{.Title}}"></td>{{end}}{{end}}
+    </tr>
+    {{end}}{{end}}
+  </table>
+</div>
+
+<h2>Repos</h2>
 {{range .Results}}
-<div class="card {{if .Skipped}}skip{{else if .Compliant}}pass{{else}}fail{{end}}">
-  <div class="card-header">
-    {{.Repo}}
-    {{if .Skipped}}<span class="badge skip">SKIPPED</span>
+<details class="card item {{if not .Checked}}skip{{else if .Compliant}}pass{{else}}fail{{end}}"
+  data-repo="{{.FullName}}" data-owner="{{.Owner}}" data-policy="{{.Policy}}" data-status="{{.Status}}" data-failing="{{failing .}}"{{if and .Checked (not .Compliant)}} open{{end}}>
+  <summary class="card-header">
+    {{if $.Owners}}{{.FullName}}{{else}}{{.Repo}}{{end}}
+    {{if not .Checked}}<span class="badge skip">{{badge .Status}}</span>
     {{else if .Compliant}}<span class="badge pass">PASS</span>
     {{else}}<span class="badge fail">FAIL</span>
     {{end}}
-    {{if and .Branch (not .Skipped)}}<span style="font-weight:normal;color:#57606a;font-size:0.85rem">({{.Branch}})</span>{{end}}
-  </div>
+    {{if and .Branch .Checked}}<span style="font-weight:normal;color:#57606a;font-size:0.85rem">({{.Branch}})</span>{{end}}
+    {{with .Policy}}<span style="font-weight:normal;color:#57606a;font-size:0.85rem">policy: {{.}}</span>{{end}}
+    {{if .Score}}<span style="margin-left:auto;font-weight:normal;color:#57606a;font-size:0.85rem">score {{score .Score}}</span>{{end}}
+  </summary>
   {{if .Error}}<div class="card-body" style="color:#57606a">{{.Error}}</div>{{end}}
-  {{if and (not .Compliant) (not .Skipped) .Diffs}}
+  {{if .SkipReason}}<div class="card-body" style="color:#57606a">{{.SkipReason}}</div>{{end}}
+  {{range .Warnings}}<div class="card-body" style="color:#9a6700">Warning: {{.}}</div>{{end}}
+  {{if .Diffs}}
   <div class="card-body">
     <table>
-      <tr><th>Rule</th><th>Expected</th><th>Actual</th><th>Status</th></tr>
+      <tr><th>Rule</th><th>Severity</th><th>Expected</th><th>Actual</th><th>Status</th>{{if hasSources .}}<th>Source</th>{{end}}</tr>
+      {{$sources := hasSources .}}
       {{range .Diffs}}
-      <tr class="{{if .Pass}}rule-pass{{else}}rule-fail{{end}}">
-        <td>{{.Rule}}</td><td>{{.Want}}</td><td>{{.Got}}</td>
-        <td>{{if .Pass}}Pass{{else}}Fail{{end}}</td>
+      <tr class="{{if .Exempt}}rule-exempt{{else if .Pass}}rule-pass{{else}}rule-fail{{end}}">
+        <td>{{.Rule}}</td><td><span class="sev {{.Severity}}">{{.Severity}}</span></td><td>{{.Want}}</td><td>{{.Got}}</td>
+        <td>{{if .Exempt}}Exempt{{else if .Pass}}Pass{{else}}Fail{{end}}</td>
+        {{if $sources}}<td>{{.Source}}</td>{{end}}
       </tr>
       {{end}}
     </table>
   </div>
   {{end}}
-</div>
+</details>
 {{end}}
 
 <footer>Generated by rampart · {{.GeneratedAt}}</footer>
+
+<script>
+(function () {
+  var search = document.getElementById("search");
+  var status = document.getElementById("status");
+  var rule = document.getElementById("rule");
+  var owner = document.getElementById("owner");
+  var policy = document.getElementById("policy");
+  var count = document.getElementById("count");
+  var items = document.querySelectorAll(".item");
+  var cards = document.querySelectorAll("details.card");
+
+  function apply() {
+    var q = search.value.trim().toLowerCase();
+    var shown = 0;
+    items.forEach(function (el) {
+      var failing = el.dataset.failing ? el.dataset.failing.split(" ") : [];
+      var match = (!q || el.dataset.repo.toLowerCase().indexOf(q) !== -1) &&
+        (!owner || !owner.value || el.dataset.owner === owner.value) &&
+        (!policy || !policy.value || el.dataset.policy === (policy.value === "-" ? "" : policy.value)) &&
+        (!status.value || el.dataset.status === status.value) &&
+        (!rule.value || failing.indexOf(rule.value) !== -1);
+      el.classList.toggle("hidden", !match);
+      if (match && el.tagName === "DETAILS") { shown++; }
+    });
+    count.textContent = shown + " of " + cards.length + " repos";
+  }
+
+  [search, status, rule, owner, policy].forEach(function (el) {
+    if (el) { el.addEventListener("input", apply); }
+  });
+  document.querySelectorAll(".owner-card").forEach(function (card) {
+    card.addEventListener("click", function () {
+      owner.value = card.dataset.owner;
+      apply();
+      window.scrollTo({ top: document.getElementById("status").offsetTop });
+    });
+  });
+  document.querySelectorAll("tr.rule-stat").forEach(function (row) {
+    row.addEventListener("click", function () {
+      rule.value = row.dataset.rule;
+      apply();
+      window.scrollTo({ top: document.getElementById("status").offsetTop });
+    });
+  });
+  document.getElementById("expand").addEventListener("click", function () {
+    cards.forEach(function (c) { c.open = true; });
+  });
+  document.getElementById("collapse").addEventListener("click", function () {
+    cards.forEach(function (c) { c.open = false; });
+  });
+  apply();
+})();
+</script>
 </body>
 </html>
 `
 
 func generateReport(path string, data ReportData) error {
-	tmpl, err := template.New("report").Parse(reportTemplate)
+	tmpl, err := template.New("report").Funcs(reportFuncs).Parse(reportTemplate)
 	if err != nil {
 		return fmt.Errorf("parsing report template: %w", err)
 	}
@@ -138,24 +458,61 @@ func generateReport(path string, data ReportData) error {
 	return nil
 }
 
-func newReportData(owner, configPath, branch string, results []RepoAuditResult) ReportData {
-	data := ReportData{
-		Owner:       owner,
-		ConfigPath:  configPath,
-		Branch:      branch,
-		GeneratedAt: time.Now().Format("2006-01-02 15:04:05 MST"),
-		Results:     results,
-		Total:       len(results),
+func newReportData(configPath string, run auditRun) ReportData {
+	results := run.Results
+	summary := summarize(results)
+
+	rules := make([]RuleStat, len(config.RuleNames))
+	index := make(map[string]int, len(config.RuleNames))
+	for i, name := range config.RuleNames {
+		rules[i] = RuleStat{Rule: name}
+		index[name] = i
 	}
 	for _, r := range results {
-		switch {
-		case r.Skipped:
-			data.Skipped++
-		case r.Error != "" || !r.Compliant:
-			data.NonCompliant++
-		default:
-			data.Compliant++
+		for _, d := range r.Diffs {
+			i, ok := index[d.Rule]
+			if !ok {
+				continue
+			}
+			rules[i].Compared++
+			if !d.Pass && !d.Exempt {
+				rules[i].Failures++
+			}
 		}
 	}
-	return data
+
+	var owners []ownerSummary
+	if len(run.Owners) > 1 {
+		owners = summarizeOwners(run)
+	}
+
+	var policies []string
+	for _, p := range run.Config.Policies {
+		for _, r := range results {
+			if r.Policy == p.Name {
+				policies = append(policies, p.Name)
+				break
+			}
+		}
+	}
+
+	return ReportData{
+		Owner:         strings.Join(run.Owners, ", "),
+		ConfigPath:    configPath,
+		Branch:        run.Config.Branch,
+		GeneratedAt:   time.Now().Format("2006-01-02 15:04:05 MST"),
+		Results:       results,
+		Rules:         rules,
+		Compliant:     summary.Compliant,
+		NonCompliant:  summary.NonCompliant + summary.Errors,
+		Skipped:       summary.Skipped,
+		Archived:      summary.Archived,
+		NoBranch:      summary.NoBranch,
+		Inactive:      summary.Inactive,
+		Total:         summary.Total,
+		Score:         formatScore(summary.Score),
+		Owners:        owners,
+		SkippedOwners: run.SkippedOwners,
+		Policies:      policies,
+	}
 }
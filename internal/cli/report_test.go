@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+func TestGenerateReportScores(t *testing.T) {
+	score := 62.5
+	run := auditRun{
+		Config: config.Default(),
+		Owners: []string{"acme"},
+		Results: []RepoAuditResult{
+			{
+				Owner:  "acme",
+				Repo:   "api",
+				Branch: "main",
+				Status: StatusNonCompliant,
+				Diffs: []config.RuleDiff{
+					{Rule: "enforce_admins", Pass: false, Want: "true", Got: "false", Severity: "high"},
+				},
+				Score: &score,
+			},
+			{Owner: "acme", Repo: "old", Status: StatusArchived, SkipReason: "read-only"},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := generateReport(path, newReportData("rampart.yaml", run)); err != nil {
+		t.Fatalf("generateReport: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+
+	if strings.Contains(html, "%!") {
+		t.Errorf("report contains a formatting error:\n%s", html)
+	}
+	if got := strings.Count(html, "score 62.5"); got != 1 {
+		t.Errorf("repo score shown %d times, want once (unscored repos show none)", got)
+	}
+}
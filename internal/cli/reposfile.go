@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/wdm0006/rampart/internal/github"
+)
+
+// repoRef names a repo listed in a --repos-file
+type repoRef struct {
+	Owner string
+	Name  string
+}
+
+// readReposFile reads the repos listed in path, or stdin for "-". The list
+// is either one owner/name per line, with blank lines and # comments
+// ignored, or a JSON array of "owner/name" strings. Objects with a
+// full_name or nameWithOwner field are accepted too, so the output of
+// `gh repo list --json nameWithOwner` can be piped in directly. Duplicates,
+// compared case-insensitively, are dropped.
+func readReposFile(path string) ([]repoRef, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repos file: %w", err)
+	}
+
+	var names []string
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) {
+		names, err = parseReposJSON(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", reposFileName(path), err)
+		}
+	} else {
+		for _, line := range strings.Split(string(data), "\n") {
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+			names = append(names, strings.TrimSpace(line))
+		}
+	}
+
+	var refs []repoRef
+	seen := make(map[string]bool)
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		owner, repo, ok := strings.Cut(name, "/")
+		if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return nil, fmt.Errorf("%s: entry %d: want owner/name, got %q", reposFileName(path), i+1, name)
+		}
+		// GitHub names are case-insensitive
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		refs = append(refs, repoRef{Owner: owner, Name: repo})
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("%s: no repos listed", reposFileName(path))
+	}
+	return refs, nil
+}
+
+// parseReposJSON reads a JSON array of repo names or repo objects
+func parseReposJSON(data []byte) ([]string, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse repo list: %w", err)
+	}
+
+	names := make([]string, 0, len(items))
+	for i, item := range items {
+		var name string
+		if err := json.Unmarshal(item, &name); err == nil {
+			names = append(names, strings.TrimSpace(name))
+			continue
+		}
+		var obj struct {
+			FullName      string `json:"full_name"`
+			NameWithOwner string `json:"nameWithOwner"`
+		}
+		if err := json.Unmarshal(item, &obj); err != nil || (obj.FullName == "" && obj.NameWithOwner == "") {
+			return nil, fmt.Errorf("entry %d: want \"owner/name\" or an object with full_name or nameWithOwner", i+1)
+		}
+		if obj.FullName != "" {
+			names = append(names, obj.FullName)
+		} else {
+			names = append(names, obj.NameWithOwner)
+		}
+	}
+	return names, nil
+}
+
+func reposFileName(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	return path
+}
+
+// fileTargets looks up every repo listed in a --repos-file. Listed repos are
+// audited even if they're forks or archived; repos that can't be looked up
+// are reported as errors. It also returns the owners in the order they first
+// appear.
+func fileTargets(path string) ([]auditTarget, []string) {
+	refs, err := readReposFile(path)
+	if err != nil {
+		exitWithError(err.Error())
+	}
+
+	fmt.Fprintf(statusOut, "Fetching %d repos listed in %s...\n", len(refs), reposFileName(path))
+	var targets []auditTarget
+	var owners []string
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if !seen[ref.Owner] {
+			seen[ref.Owner] = true
+			owners = append(owners, ref.Owner)
+		}
+		r, err := github.GetRepo(ref.Owner, ref.Name)
+		if err != nil {
+			targets = append(targets, auditTarget{owner: ref.Owner, repo: github.Repo{Name: ref.Name}, err: err, listed: true})
+			continue
+		}
+		targets = append(targets, auditTarget{owner: ref.Owner, repo: r, listed: true})
+	}
+	return targets, owners
+}
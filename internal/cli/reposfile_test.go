@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadReposFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []repoRef
+		wantErr string
+	}{
+		{
+			name: "lines with comments and blank lines",
+			data: "# audited repos\nacme/api\n\n  acme/web  # trailing comment\n\t\n",
+			want: []repoRef{{"acme", "api"}, {"acme", "web"}},
+		},
+		{
+			name: "duplicates are dropped, ignoring case",
+			data: "acme/api\nglobex/api\nAcme/API\nacme/api\n",
+			want: []repoRef{{"acme", "api"}, {"globex", "api"}},
+		},
+		{
+			name: "JSON strings and objects",
+			data: `["acme/api", {"full_name": "acme/web"}, {"nameWithOwner": "globex/cli"}, "acme/api"]`,
+			want: []repoRef{{"acme", "api"}, {"acme", "web"}, {"globex", "cli"}},
+		},
+		{name: "bare name", data: "acme/api\napi\n", wantErr: `entry 2: want owner/name, got "api"`},
+		{name: "too many parts", data: "acme/api/extra\n", wantErr: `entry 1: want owner/name`},
+		{name: "missing owner", data: "/api\n", wantErr: `entry 1: want owner/name`},
+		{name: "JSON object without a name", data: `[{"name": "api"}]`, wantErr: "entry 1: want"},
+		{name: "only comments", data: "# nothing yet\n\n", wantErr: "no repos listed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "repos.txt")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readReposFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readReposFile: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readReposFile = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
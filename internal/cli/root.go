@@ -5,6 +5,8 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/wdm0006/rampart/internal/config"
+	"github.com/wdm0006/rampart/internal/github"
 )
 
 var version = "dev"
@@ -47,8 +49,15 @@ func Execute() error {
 
 func init() {
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(configCmd)
+
+	config.FetchRemote = github.GetFileContents
 	rootCmd.AddCommand(auditCmd)
 	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(diffCmd)
 }
 
 func exitWithError(msg string) {
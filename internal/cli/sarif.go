@@ -0,0 +1,221 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// ruleDescriptions gives the one-line help shown for each rule in code scanning
+var ruleDescriptions = map[string]string{
+	"require_pull_request":             "Changes must be made through a pull request",
+	"required_approvals":               "Pull requests need the configured number of approving reviews",
+	"dismiss_stale_reviews":            "New commits dismiss existing approvals",
+	"require_code_owner_reviews":       "Pull requests need a review from a code owner",
+	"require_status_checks":            "Status checks must pass before merging",
+	"strict_status_checks":             "Branches must be up to date before merging",
+	"required_checks":                  "The configured status checks are required",
+	"enforce_admins":                   "Protection rules also apply to administrators",
+	"allow_force_pushes":               "Force pushes are allowed or blocked as configured",
+	"allow_deletions":                  "Branch deletion is allowed or blocked as configured",
+	"required_linear_history":          "Merge commits are blocked",
+	"required_conversation_resolution": "Review conversations must be resolved before merging",
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	Properties           sarifRuleProps     `json:"properties"`
+}
+
+type sarifRuleProps struct {
+	SecuritySeverity string   `json:"security-severity"`
+	Tags             []string `json:"tags"`
+}
+
+// sarifLevels maps rule severities to SARIF result levels
+var sarifLevels = map[string]string{
+	config.SeverityCritical: "error",
+	config.SeverityHigh:     "error",
+	config.SeverityMedium:   "warning",
+	config.SeverityLow:      "note",
+}
+
+// securitySeverities maps rule severities to the CVSS-style scores code
+// scanning uses to label alerts critical, high, medium or low
+var securitySeverities = map[string]string{
+	config.SeverityCritical: "9.5",
+	config.SeverityHigh:     "8.0",
+	config.SeverityMedium:   "5.5",
+	config.SeverityLow:      "2.0",
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// writeSARIF writes failing rules as a SARIF log. Code scanning needs a file
+// location for every alert, so results point at the line that sets the rule:
+// in the config, a file it extends, or the repo's policy. The repo and branch
+// are carried as a logical location.
+func writeSARIF(w io.Writer, configPath string, cfg config.Config, results []RepoAuditResult) error {
+	ruleIndex := make(map[string]int, len(config.RuleNames))
+	rules := make([]sarifRule, len(config.RuleNames))
+	for i, name := range config.RuleNames {
+		severity := cfg.Severity(name)
+		ruleIndex[name] = i
+		rules[i] = sarifRule{
+			ID:                   name,
+			ShortDescription:     sarifMessage{Text: ruleDescriptions[name]},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevels[severity]},
+			Properties: sarifRuleProps{
+				SecuritySeverity: securitySeverities[severity],
+				Tags:             []string{"security", "branch-protection"},
+			},
+		}
+	}
+
+	locations := config.LocateRules(configPath)
+
+	sarifResults := []sarifResult{}
+	for _, r := range results {
+		if r.Status != StatusNonCompliant {
+			continue
+		}
+		fqn := fmt.Sprintf("%s@%s", r.FullName(), r.Branch)
+		for _, d := range config.Failing(r.Diffs) {
+			loc := locations.For(r.Policy, d.Rule)
+			sarifResults = append(sarifResults, sarifResult{
+				RuleID:    d.Rule,
+				RuleIndex: ruleIndex[d.Rule],
+				Level:     sarifLevels[d.Severity],
+				Message: sarifMessage{
+					Text: fmt.Sprintf("%s (%s): %s is %s, want %s", r.FullName(), r.Branch, d.Rule, d.Got, d.Want),
+				},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifact(loc.File),
+						Region:           sarifRegion{StartLine: loc.Line},
+					},
+					LogicalLocations: []sarifLogicalLocation{{
+						Name:               r.Repo,
+						FullyQualifiedName: fqn,
+						Kind:               "module",
+					}},
+				}},
+				PartialFingerprints: map[string]string{
+					"rampartRule/v1": fqn + ":" + d.Rule,
+				},
+			})
+		}
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "rampart",
+				Version:        version,
+				InformationURI: "https://github.com/wdm0006/rampart",
+				Rules:          rules,
+			}},
+			Results: sarifResults,
+		}},
+	}
+	return writeJSON(w, log)
+}
+
+// sarifArtifact names a config file for code scanning, which resolves
+// relative URIs against the repository root. Files under the working
+// directory are given relative to it; others get an absolute file:// URI.
+func sarifArtifact(path string) sarifArtifactLocation {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return sarifArtifactLocation{URI: filepath.ToSlash(path)}
+	}
+	if wd, err := os.Getwd(); err == nil {
+		rel, err := filepath.Rel(wd, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return sarifArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: "%SRCROOT%"}
+		}
+	}
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		// Windows drive paths, e.g. file:///C:/ci/rampart.yaml
+		p = "/" + p
+	}
+	u := url.URL{Scheme: "file", Path: p}
+	return sarifArtifactLocation{URI: u.String()}
+}
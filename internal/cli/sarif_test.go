@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+func TestSARIFArtifact(t *testing.T) {
+	if got := sarifArtifact("./configs/rampart.yaml"); got.URI != "configs/rampart.yaml" || got.URIBaseID != "%SRCROOT%" {
+		t.Errorf("relative path: got %+v", got)
+	}
+
+	outside := filepath.Join(t.TempDir(), "rampart.yaml")
+	got := sarifArtifact(outside)
+	if !strings.HasPrefix(got.URI, "file:///") || got.URIBaseID != "" {
+		t.Errorf("path outside the working directory: got %+v, want a file:// URI", got)
+	}
+}
+
+func TestWriteSARIFLocatesRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rampart.yaml")
+	src := "severities:\n  enforce_admins: critical\nrules:\n  require_pull_request: true\n  enforce_admins: true\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []RepoAuditResult{{
+		Owner:  "acme",
+		Repo:   "api",
+		Branch: "main",
+		Status: StatusNonCompliant,
+		Diffs: []config.RuleDiff{
+			{Rule: "enforce_admins", Want: "true", Got: "false", Severity: "critical"},
+		},
+	}}
+	var b bytes.Buffer
+	if err := writeSARIF(&b, path, cfg, results); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(b.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	alerts := log.Runs[0].Results
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	if line := alerts[0].Locations[0].PhysicalLocation.Region.StartLine; line != 5 {
+		t.Errorf("enforce_admins located at line %d, want 5", line)
+	}
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/wdm0006/rampart/internal/config"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check a rampart.yaml config for errors",
+	Long: `Strictly parses a config file, rejecting unknown keys, and checks that the rules are
+consistent (e.g. required_checks only with require_status_checks). Exits non-zero if the config is invalid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath, _ := cmd.Flags().GetString("config")
+
+		if _, err := config.Load(configPath); err != nil {
+			exitWithError(err.Error())
+		}
+
+		fmt.Printf("%s is valid\n", configPath)
+	},
+}
+
+func init() {
+	validateCmd.Flags().String("config", "rampart.yaml", "Path to config file")
+}
@@ -1,16 +1,30 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the rampart configuration file
 type Config struct {
-	Branch string `yaml:"branch"`
-	Rules  Rules  `yaml:"rules"`
+	Extends string `yaml:"extends,omitempty"`
+	// Owners are the users or orgs to audit when --owner isn't given
+	Owners []string `yaml:"owners,omitempty"`
+	Branch string   `yaml:"branch"`
+	Rules  Rules    `yaml:"rules"`
+
+	Select     Selection          `yaml:"select,omitempty"`
+	Policies   []Policy           `yaml:"policies,omitempty"`
+	RepoConfig RepoConfig         `yaml:"repo_config,omitempty"`
+	Exemptions []Exemption        `yaml:"exemptions,omitempty"`
+	Severities map[string]string  `yaml:"severities,omitempty"`
+	Weights    map[string]float64 `yaml:"weights,omitempty"`
 }
 
 // Rules represents the desired branch protection rules
@@ -31,10 +45,13 @@ type Rules struct {
 
 // RuleDiff represents a single rule comparison result
 type RuleDiff struct {
-	Rule string
-	Pass bool
-	Want string
-	Got  string
+	Rule     string `json:"rule"`
+	Pass     bool   `json:"pass"`
+	Want     string `json:"want"`
+	Got      string `json:"got"`
+	Source   string `json:"source,omitempty"`
+	Exempt   bool   `json:"exempt,omitempty"`
+	Severity string `json:"severity,omitempty"`
 }
 
 // Default returns a Config with sensible defaults
@@ -58,16 +75,23 @@ func Default() Config {
 	}
 }
 
-// Load reads and parses a rampart config file
+// Load reads, strictly parses and validates a rampart config file, resolving
+// any extends chain into a single config
 func Load(path string) (Config, error) {
-	data, err := os.ReadFile(path)
+	chain, err := loadChain(path, map[string]bool{})
 	if err != nil {
-		return Config{}, fmt.Errorf("failed to read config: %w", err)
+		return Config{}, err
+	}
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, src := range chain {
+		merged = mergeNodes(merged, src.doc)
 	}
+	removeKey(merged, "extends")
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return Config{}, fmt.Errorf("failed to parse config: %w", err)
+	if err := merged.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("%s: failed to parse config: %w", path, err)
 	}
 
 	if cfg.Branch == "" {
@@ -77,16 +101,79 @@ func Load(path string) (Config, error) {
 		cfg.Rules.RequiredChecks = []string{}
 	}
 
+	if err := cfg.validate(chain); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
 	return cfg, nil
 }
 
+// parseSource parses a single config file and expands environment variables
+// in its values. Unknown keys are rejected so a typo doesn't silently fall
+// back to the zero value.
+func parseSource(name string, data []byte) (source, Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return source{}, Config{}, fmt.Errorf("%s: failed to parse config: %w", name, err)
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	if err := expandEnv(root); err != nil {
+		return source{}, Config{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := checkKnownFields(data); err != nil {
+		return source{}, Config{}, fmt.Errorf("%s: failed to parse config: %w", name, err)
+	}
+
+	var cfg Config
+	if err := root.Decode(&cfg); err != nil {
+		return source{}, Config{}, fmt.Errorf("%s: failed to parse config: %w", name, err)
+	}
+	return source{name: name, doc: root}, cfg, nil
+}
+
+// checkKnownFields reports keys that aren't part of the config. It decodes
+// the file as written, before environment variables are expanded, so only
+// unknown-field errors count: an unexpanded ${VAR} may not fit its type yet.
+func checkKnownFields(data []byte) error {
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(&cfg)
+	var typeErr *yaml.TypeError
+	if err == nil || errors.Is(err, io.EOF) || !errors.As(err, &typeErr) {
+		return nil
+	}
+	var unknown []string
+	for _, e := range typeErr.Errors {
+		if strings.Contains(e, "not found in type") {
+			unknown = append(unknown, e)
+		}
+	}
+	if len(unknown) > 0 {
+		return &yaml.TypeError{Errors: unknown}
+	}
+	return nil
+}
+
 // WriteDefault writes the default config to a file
 func WriteDefault(path string) error {
-	cfg := Default()
+	return Write(path, Default())
+}
+
+// SchemaURL is the published JSON Schema for rampart.yaml
+const SchemaURL = "https://raw.githubusercontent.com/wdm0006/rampart/main/schema/rampart.schema.json"
+
+// Write writes a config to a file, with a modeline pointing editors at the
+// JSON Schema
+func Write(path string, cfg Config) error {
 	data, err := yaml.Marshal(&cfg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
+	data = append([]byte("# yaml-language-server: $schema="+SchemaURL+"\n"), data...)
 
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
@@ -191,6 +278,22 @@ func RulesFromResponse(resp ProtectionResponse) Rules {
 	return r
 }
 
+// RuleNames lists every rule in the order Compare reports them
+var RuleNames = []string{
+	"require_pull_request",
+	"required_approvals",
+	"dismiss_stale_reviews",
+	"require_code_owner_reviews",
+	"require_status_checks",
+	"strict_status_checks",
+	"required_checks",
+	"enforce_admins",
+	"allow_force_pushes",
+	"allow_deletions",
+	"required_linear_history",
+	"required_conversation_resolution",
+}
+
 // Compare compares desired rules against actual rules and returns diffs
 func Compare(desired, actual Rules) []RuleDiff {
 	var diffs []RuleDiff
@@ -246,3 +349,14 @@ func Compare(desired, actual Rules) []RuleDiff {
 
 	return diffs
 }
+
+// Failing returns only the diffs that did not pass and aren't exempt
+func Failing(diffs []RuleDiff) []RuleDiff {
+	var failing []RuleDiff
+	for _, d := range diffs {
+		if !d.Pass && !d.Exempt {
+			failing = append(failing, d)
+		}
+	}
+	return failing
+}
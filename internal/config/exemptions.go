@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// exemptionDateLayout is the format of Exemption.Expires
+const exemptionDateLayout = "2006-01-02"
+
+// Exemption excuses a repo, or a single rule on a repo, from the policy until
+// an expiry date
+type Exemption struct {
+	// Repo is a repo name, or owner/name to match a single owner's repo
+	Repo string `yaml:"repo"`
+	// Rule is empty to exempt every rule on the repo
+	Rule     string `yaml:"rule,omitempty"`
+	Reason   string `yaml:"reason"`
+	Approver string `yaml:"approver"`
+	// Expires is the last day (YYYY-MM-DD, UTC) the exemption applies
+	Expires string `yaml:"expires"`
+}
+
+// ExpiresOn parses the expiry date
+func (e Exemption) ExpiresOn() (time.Time, error) {
+	return time.Parse(exemptionDateLayout, e.Expires)
+}
+
+// DaysRemaining returns the whole days left before the exemption expires:
+// 0 on its last day, negative once expired
+func (e Exemption) DaysRemaining(now time.Time) int {
+	expires, err := e.ExpiresOn()
+	if err != nil {
+		return -1
+	}
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return int(expires.Sub(today).Hours() / 24)
+}
+
+// Expired reports whether the exemption no longer applies
+func (e Exemption) Expired(now time.Time) bool {
+	return e.DaysRemaining(now) < 0
+}
+
+// matches reports whether the exemption names a repo
+func (e Exemption) matches(owner, repo string) bool {
+	return e.Repo == repo || e.Repo == owner+"/"+repo
+}
+
+// covers reports whether the exemption applies to a rule on a repo
+func (e Exemption) covers(owner, repo, rule string) bool {
+	return e.matches(owner, repo) && (e.Rule == "" || e.Rule == rule)
+}
+
+// ActiveExemptions returns the exemptions that haven't expired
+func (c Config) ActiveExemptions(now time.Time) []Exemption {
+	var active []Exemption
+	for _, e := range c.Exemptions {
+		if !e.Expired(now) {
+			active = append(active, e)
+		}
+	}
+	return active
+}
+
+// ApplyExemptions marks the diffs covered by an active exemption as exempt
+// and keeps the repo's current value for those rules in desired, so apply
+// leaves them alone. Expired exemptions no longer apply; a warning is
+// returned for each so the rules they covered show up as failures with an
+// explanation.
+func ApplyExemptions(exemptions []Exemption, owner, repo string, diffs []RuleDiff, desired *Rules, actual Rules, now time.Time) []string {
+	var warnings []string
+	for _, e := range exemptions {
+		if !e.matches(owner, repo) {
+			continue
+		}
+		if e.Expired(now) {
+			scope := "all rules"
+			if e.Rule != "" {
+				scope = e.Rule
+			}
+			warnings = append(warnings, fmt.Sprintf("exemption for %s expired on %s", scope, e.Expires))
+			continue
+		}
+		for i := range diffs {
+			if e.covers(owner, repo, diffs[i].Rule) {
+				diffs[i].Exempt = true
+				copyRule(desired, actual, diffs[i].Rule)
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysRemainingUsesUTCDate(t *testing.T) {
+	e := Exemption{Repo: "api", Expires: "2026-06-30"}
+	// 08:00 on July 1 in UTC+9 is still June 30 in UTC, the last day
+	tokyo := time.FixedZone("UTC+9", 9*60*60)
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, tokyo)
+
+	if got := e.DaysRemaining(now); got != 0 {
+		t.Errorf("DaysRemaining = %d, want 0", got)
+	}
+	if e.Expired(now) {
+		t.Error("Expired = true on the exemption's last day")
+	}
+}
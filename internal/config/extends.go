@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// remotePrefix marks an extends reference to a file in a GitHub repo:
+// github:OWNER/REPO/PATH[@REF]
+const remotePrefix = "github:"
+
+// FetchRemote retrieves a file from a GitHub repo at a ref (empty for the
+// default branch). It is set by the cli package so config doesn't depend on
+// the gh client; remote extends fail while it's nil.
+var FetchRemote func(owner, repo, path, ref string) ([]byte, error)
+
+// source is one config file in an extends chain
+type source struct {
+	name string
+	doc  *yaml.Node
+}
+
+// loadChain reads a config file and everything it extends, base first
+func loadChain(name string, seen map[string]bool) ([]source, error) {
+	if seen[name] {
+		return nil, fmt.Errorf("%s: extends cycle", name)
+	}
+	seen[name] = true
+
+	data, err := readRef(name)
+	if err != nil {
+		return nil, err
+	}
+	src, cfg, err := parseSource(name, data)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Extends == "" {
+		return []source{src}, nil
+	}
+
+	base, err := resolveRef(name, cfg.Extends)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	chain, err := loadChain(base, seen)
+	if err != nil {
+		return nil, err
+	}
+	return append(chain, src), nil
+}
+
+func readRef(name string) ([]byte, error) {
+	if !strings.HasPrefix(name, remotePrefix) {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		return data, nil
+	}
+
+	owner, repo, p, ref, err := parseRemote(name)
+	if err != nil {
+		return nil, err
+	}
+	if FetchRemote == nil {
+		return nil, fmt.Errorf("%s: remote configs are not supported here", name)
+	}
+	data, err := FetchRemote(owner, repo, p, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// resolveRef resolves an extends target relative to the file containing it.
+// Relative paths in a remote file point into the same repo and ref.
+func resolveRef(from, target string) (string, error) {
+	if strings.HasPrefix(target, remotePrefix) {
+		if _, _, _, _, err := parseRemote(target); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+
+	if strings.HasPrefix(from, remotePrefix) {
+		owner, repo, p, ref, err := parseRemote(from)
+		if err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("%s%s/%s/%s", remotePrefix, owner, repo, path.Join(path.Dir(p), target))
+		if ref != "" {
+			name += "@" + ref
+		}
+		return name, nil
+	}
+
+	if filepath.IsAbs(target) {
+		return target, nil
+	}
+	return filepath.Join(filepath.Dir(from), target), nil
+}
+
+// parseRemote splits github:OWNER/REPO/PATH[@REF] into its parts
+func parseRemote(name string) (owner, repo, p, ref string, err error) {
+	spec := strings.TrimPrefix(name, remotePrefix)
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		spec, ref = spec[:i], spec[i+1:]
+	}
+	parts := strings.SplitN(spec, "/", 3)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", "", fmt.Errorf("invalid remote config %q (want %sOWNER/REPO/PATH[@REF])", name, remotePrefix)
+	}
+	return parts[0], parts[1], parts[2], ref, nil
+}
+
+// mergeNodes deep-merges over onto base. Mappings merge key by key; any
+// other value in over, including lists, replaces the base value.
+func mergeNodes(base, over *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || over.Kind != yaml.MappingNode {
+		return over
+	}
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: base.Tag, Line: base.Line, Column: base.Column}
+	merged.Content = append(merged.Content, base.Content...)
+	for i := 0; i+1 < len(over.Content); i += 2 {
+		key, value := over.Content[i], over.Content[i+1]
+		replaced := false
+		for j := 0; j+1 < len(merged.Content); j += 2 {
+			if merged.Content[j].Value == key.Value {
+				merged.Content[j+1] = mergeNodes(merged.Content[j+1], value)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+	return merged
+}
+
+// removeKey deletes a top-level key from a mapping node
+func removeKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
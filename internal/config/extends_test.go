@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeNodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		base, over string
+		want       string
+	}{
+		{"maps merge key by key", "a: 1\nb: {c: 2, d: 3}", "b: {d: 4}\ne: 5", "a: 1\nb: {c: 2, d: 4}\ne: 5"},
+		{"sequences are replaced", "a: [1, 2]", "a: [3]", "a: [3]"},
+		{"a scalar replaces a map", "a: {b: 1}", "a: off", "a: off"},
+		{"a map replaces a scalar", "a: off", "a: {b: 1}", "a: {b: 1}"},
+		{"an empty override keeps the base", "a: 1", "{}", "a: 1"},
+	}
+	parse := func(src string) *yaml.Node {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc.Content[0]
+	}
+	decode := func(n *yaml.Node) interface{} {
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decode(mergeNodes(parse(tt.base), parse(tt.over)))
+			if want := decode(parse(tt.want)); !reflect.DeepEqual(got, want) {
+				t.Errorf("merged = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadExtendsRelativePath(t *testing.T) {
+	cfg, err := Load("testdata/extends/team/rampart.yaml")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Branch != "main" || !cfg.Rules.EnforceAdmins || !cfg.Rules.RequirePullRequest {
+		t.Errorf("base settings not inherited: %+v", cfg)
+	}
+	if cfg.Rules.RequiredApprovals != 2 {
+		t.Errorf("required_approvals = %d, want the override 2", cfg.Rules.RequiredApprovals)
+	}
+	if want := []string{"ci/test"}; !reflect.DeepEqual(cfg.Rules.RequiredChecks, want) {
+		t.Errorf("required_checks = %v, want the override %v", cfg.Rules.RequiredChecks, want)
+	}
+	if want := map[string]string{"enforce_admins": "high", "allow_deletions": "low"}; !reflect.DeepEqual(cfg.Severities, want) {
+		t.Errorf("severities = %v, want both files' %v", cfg.Severities, want)
+	}
+	if cfg.Extends != "" {
+		t.Errorf("extends = %q, want it removed from the resolved config", cfg.Extends)
+	}
+}
+
+func TestLoadExtendsRemote(t *testing.T) {
+	var fetched []string
+	FetchRemote = func(owner, repo, path, ref string) ([]byte, error) {
+		fetched = append(fetched, fmt.Sprintf("%s/%s/%s@%s", owner, repo, path, ref))
+		switch path {
+		case "org/base.yaml":
+			// Relative to the remote file, at the same ref
+			return []byte("extends: common.yaml\nrules:\n  required_approvals: 2\n"), nil
+		case "org/common.yaml":
+			return []byte("branch: main\nrules:\n  enforce_admins: true\n  required_approvals: 1\n"), nil
+		}
+		return nil, fmt.Errorf("not found")
+	}
+	t.Cleanup(func() { FetchRemote = nil })
+
+	cfg, err := Load("testdata/extends/remote.yaml")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if want := []string{"acme/policies/org/base.yaml@v1", "acme/policies/org/common.yaml@v1"}; !reflect.DeepEqual(fetched, want) {
+		t.Errorf("fetched %v, want %v", fetched, want)
+	}
+	if cfg.Branch != "main" || !cfg.Rules.EnforceAdmins || cfg.Rules.RequiredApprovals != 3 {
+		t.Errorf("resolved config = %+v, want branch main, enforce_admins and 3 approvals", cfg)
+	}
+}
+
+func TestLoadExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name, path, want string
+	}{
+		{"cycle", "testdata/extends/cycle/a.yaml", "extends cycle"},
+		{"missing base", "testdata/extends/missing.yaml", "nowhere.yaml"},
+		{"remote without a fetcher", "testdata/extends/remote.yaml", "remote configs are not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
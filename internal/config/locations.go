@@ -0,0 +1,92 @@
+package config
+
+import "strings"
+
+// Location is a line in a local config file
+type Location struct {
+	File string
+	Line int
+}
+
+// RuleLocations records where each rule is set in a config and the files it
+// extends, for pointing reports at the line to change
+type RuleLocations struct {
+	rules    map[string]Location
+	policies map[string]map[string]Location
+	fallback Location
+}
+
+// LocateRules finds where each rule is set in the config at path, following
+// its extends chain. A config that can't be loaded has no locations.
+func LocateRules(path string) RuleLocations {
+	l := RuleLocations{
+		rules:    make(map[string]Location),
+		policies: make(map[string]map[string]Location),
+		fallback: Location{File: path, Line: 1},
+	}
+	chain, err := loadChain(path, map[string]bool{})
+	if err != nil {
+		return l
+	}
+
+	for _, rule := range RuleNames {
+		for i := len(chain) - 1; i >= 0; i-- {
+			if line := keyLine(chain[i].doc, "rules", rule); line > 0 {
+				l.rules[rule] = localLocation(chain, i, line)
+				break
+			}
+		}
+	}
+
+	// Sequences aren't merged, so the last file to list policies defines them
+	for i := len(chain) - 1; i >= 0; i-- {
+		_, policies := lookupKey(chain[i].doc, "policies")
+		if policies == nil {
+			continue
+		}
+		for _, item := range policies.Content {
+			_, name := lookupKey(item, "name")
+			if name == nil {
+				continue
+			}
+			rules := make(map[string]Location)
+			for _, rule := range RuleNames {
+				if line := keyLine(item, "rules", rule); line > 0 {
+					rules[rule] = localLocation(chain, i, line)
+				}
+			}
+			l.policies[name.Value] = rules
+		}
+		break
+	}
+	return l
+}
+
+// For returns where a rule is set for repos held to a policy ("" for the
+// base rules). Rules the policy doesn't set fall back to the base rules.
+// Rules set in a remote file point at the extends key that pulls it in, and
+// rules set nowhere point at the first line of the config.
+func (l RuleLocations) For(policy, rule string) Location {
+	if loc, ok := l.policies[policy][rule]; ok {
+		return loc
+	}
+	if loc, ok := l.rules[rule]; ok {
+		return loc
+	}
+	return l.fallback
+}
+
+// localLocation points at line in chain[i], or for a remote file, at the
+// extends key of the nearest local file built on it
+func localLocation(chain []source, i, line int) Location {
+	if !strings.HasPrefix(chain[i].name, remotePrefix) {
+		return Location{File: chain[i].name, Line: line}
+	}
+	for j := i + 1; j < len(chain); j++ {
+		if !strings.HasPrefix(chain[j].name, remotePrefix) {
+			return Location{File: chain[j].name, Line: keyLine(chain[j].doc, "extends")}
+		}
+	}
+	// Only reachable when the config itself is remote
+	return Location{File: chain[len(chain)-1].name, Line: 1}
+}
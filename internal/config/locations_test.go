@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes files under a temp dir and returns the dir
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLocateRules(t *testing.T) {
+	FetchRemote = func(owner, repo, path, ref string) ([]byte, error) {
+		return []byte("rules:\n  required_linear_history: true\n"), nil
+	}
+	t.Cleanup(func() { FetchRemote = nil })
+
+	dir := writeFiles(t, map[string]string{
+		"base/org.yaml": `extends: github:acme/policy/base.yaml
+rules:
+  enforce_admins: true
+  required_approvals: 1
+`,
+		"rampart.yaml": `severities:
+  enforce_admins: critical
+  require_pull_request: high
+extends: base/org.yaml
+rules:
+  require_pull_request: true
+  # required_approvals is set below
+  required_approvals: 2
+policies:
+  - name: strict
+    match:
+      properties:
+        tier: critical
+    rules:
+      required_approvals: 3
+`,
+	})
+	top := filepath.Join(dir, "rampart.yaml")
+	base := filepath.Join(dir, "base", "org.yaml")
+
+	l := LocateRules(top)
+	tests := []struct {
+		policy, rule string
+		want         Location
+	}{
+		{"", "require_pull_request", Location{top, 6}},
+		{"", "required_approvals", Location{top, 8}},
+		{"", "enforce_admins", Location{base, 3}},
+		{"", "required_linear_history", Location{base, 1}},
+		{"", "allow_deletions", Location{top, 1}},
+		{"strict", "required_approvals", Location{top, 15}},
+		{"strict", "require_pull_request", Location{top, 6}},
+	}
+	for _, tt := range tests {
+		if got := l.For(tt.policy, tt.rule); got != tt.want {
+			t.Errorf("For(%q, %s) = %v, want %v", tt.policy, tt.rule, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRepoConfigPath is where repos keep their own policy file
+const DefaultRepoConfigPath = ".github/rampart.yaml"
+
+// Rule sources reported on each RuleDiff when per-repo policy files are on
+const (
+	SourceCentral = "central"
+	SourceRepo    = "repo"
+)
+
+// RepoConfig controls per-repo policy files merged on top of the central rules
+type RepoConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path,omitempty"`
+	// TightenOnly lists rules a repo may make stricter but never looser
+	TightenOnly []string `yaml:"tighten_only,omitempty"`
+}
+
+// FilePath returns the configured policy file path, or the default
+func (c RepoConfig) FilePath() string {
+	if c.Path == "" {
+		return DefaultRepoConfigPath
+	}
+	return c.Path
+}
+
+// Override is the result of merging a repo's policy file over central rules
+type Override struct {
+	Rules Rules
+	// Sources maps every rule to where its effective value came from
+	Sources map[string]string
+	// Rejected describes tighten-only rules the repo tried to loosen; those
+	// keep the central value
+	Rejected []string
+}
+
+// repoFile is the shape of a per-repo policy file
+type repoFile struct {
+	Rules Rules `yaml:"rules"`
+}
+
+// ApplyRepoOverride merges a repo policy file over the central rules. Only
+// rules the file sets are changed, and rules listed in tightenOnly are kept at
+// the central value if the repo's value is looser.
+func ApplyRepoOverride(central Rules, data []byte, tightenOnly []string) (Override, error) {
+	file := repoFile{Rules: central}
+	file.Rules.RequiredChecks = append([]string{}, central.RequiredChecks...)
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return Override{}, fmt.Errorf("failed to parse repo policy: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Override{}, fmt.Errorf("failed to parse repo policy: %w", err)
+	}
+
+	ov := Override{Rules: file.Rules, Sources: make(map[string]string, len(RuleNames))}
+	for _, rule := range RuleNames {
+		ov.Sources[rule] = SourceCentral
+		if keyLine(&doc, "rules", rule) > 0 {
+			ov.Sources[rule] = SourceRepo
+		}
+	}
+
+	for _, rule := range tightenOnly {
+		if ov.Sources[rule] != SourceRepo || !isLooser(rule, central, ov.Rules) {
+			continue
+		}
+		copyRule(&ov.Rules, central, rule)
+		ov.Sources[rule] = SourceCentral
+		ov.Rejected = append(ov.Rejected, fmt.Sprintf("repo policy may only tighten %s", rule))
+	}
+
+	if err := (Config{Rules: ov.Rules}).Validate(); err != nil {
+		return Override{}, fmt.Errorf("repo policy: %w", err)
+	}
+	return ov, nil
+}
+
+// isLooser reports whether candidate's value for rule is less strict than base's
+func isLooser(rule string, base, candidate Rules) bool {
+	weaker := func(base, candidate bool) bool { return base && !candidate }
+
+	switch rule {
+	case "require_pull_request":
+		return weaker(base.RequirePullRequest, candidate.RequirePullRequest)
+	case "required_approvals":
+		return candidate.RequiredApprovals < base.RequiredApprovals
+	case "dismiss_stale_reviews":
+		return weaker(base.DismissStaleReviews, candidate.DismissStaleReviews)
+	case "require_code_owner_reviews":
+		return weaker(base.RequireCodeOwnerReviews, candidate.RequireCodeOwnerReviews)
+	case "require_status_checks":
+		return weaker(base.RequireStatusChecks, candidate.RequireStatusChecks)
+	case "strict_status_checks":
+		return weaker(base.StrictStatusChecks, candidate.StrictStatusChecks)
+	case "required_checks":
+		have := make(map[string]bool)
+		for _, c := range candidate.RequiredChecks {
+			have[c] = true
+		}
+		for _, c := range base.RequiredChecks {
+			if !have[c] {
+				return true
+			}
+		}
+		return false
+	case "enforce_admins":
+		return weaker(base.EnforceAdmins, candidate.EnforceAdmins)
+	case "allow_force_pushes":
+		return !base.AllowForcePushes && candidate.AllowForcePushes
+	case "allow_deletions":
+		return !base.AllowDeletions && candidate.AllowDeletions
+	case "required_linear_history":
+		return weaker(base.RequiredLinearHistory, candidate.RequiredLinearHistory)
+	case "required_conversation_resolution":
+		return weaker(base.RequiredConversationResolution, candidate.RequiredConversationResolution)
+	}
+	return false
+}
+
+// copyRule sets dst's value for rule to src's
+func copyRule(dst *Rules, src Rules, rule string) {
+	switch rule {
+	case "require_pull_request":
+		dst.RequirePullRequest = src.RequirePullRequest
+	case "required_approvals":
+		dst.RequiredApprovals = src.RequiredApprovals
+	case "dismiss_stale_reviews":
+		dst.DismissStaleReviews = src.DismissStaleReviews
+	case "require_code_owner_reviews":
+		dst.RequireCodeOwnerReviews = src.RequireCodeOwnerReviews
+	case "require_status_checks":
+		dst.RequireStatusChecks = src.RequireStatusChecks
+	case "strict_status_checks":
+		dst.StrictStatusChecks = src.StrictStatusChecks
+	case "required_checks":
+		dst.RequiredChecks = append([]string{}, src.RequiredChecks...)
+	case "enforce_admins":
+		dst.EnforceAdmins = src.EnforceAdmins
+	case "allow_force_pushes":
+		dst.AllowForcePushes = src.AllowForcePushes
+	case "allow_deletions":
+		dst.AllowDeletions = src.AllowDeletions
+	case "required_linear_history":
+		dst.RequiredLinearHistory = src.RequiredLinearHistory
+	case "required_conversation_resolution":
+		dst.RequiredConversationResolution = src.RequiredConversationResolution
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// strictRules is a central policy with every rule at its strictest
+func strictRules() Rules {
+	return Rules{
+		RequirePullRequest:             true,
+		RequiredApprovals:              2,
+		DismissStaleReviews:            true,
+		RequireCodeOwnerReviews:        true,
+		RequireStatusChecks:            true,
+		StrictStatusChecks:             true,
+		RequiredChecks:                 []string{"ci/build", "ci/lint"},
+		EnforceAdmins:                  true,
+		AllowForcePushes:               false,
+		AllowDeletions:                 false,
+		RequiredLinearHistory:          true,
+		RequiredConversationResolution: true,
+	}
+}
+
+func TestApplyRepoOverrideRejectsLoosening(t *testing.T) {
+	// Each value is less strict than strictRules; for allow_force_pushes and
+	// allow_deletions that means true
+	loosen := map[string]string{
+		"require_pull_request":             "false",
+		"required_approvals":               "1",
+		"dismiss_stale_reviews":            "false",
+		"require_code_owner_reviews":       "false",
+		"require_status_checks":            "false",
+		"strict_status_checks":             "false",
+		"required_checks":                  "[ci/build]",
+		"enforce_admins":                   "false",
+		"allow_force_pushes":               "true",
+		"allow_deletions":                  "true",
+		"required_linear_history":          "false",
+		"required_conversation_resolution": "false",
+	}
+	for _, rule := range RuleNames {
+		if _, ok := loosen[rule]; !ok {
+			t.Errorf("no loosening case for %s", rule)
+		}
+	}
+
+	for rule, value := range loosen {
+		t.Run(rule, func(t *testing.T) {
+			central := strictRules()
+			ov, err := ApplyRepoOverride(central, []byte("rules:\n  "+rule+": "+value+"\n"), RuleNames)
+			if err != nil {
+				t.Fatalf("ApplyRepoOverride: %v", err)
+			}
+			if !reflect.DeepEqual(ov.Rules, central) {
+				t.Errorf("rules = %+v, want the central rules unchanged", ov.Rules)
+			}
+			if ov.Sources[rule] != SourceCentral {
+				t.Errorf("source = %q, want %q", ov.Sources[rule], SourceCentral)
+			}
+			if len(ov.Rejected) != 1 || !strings.Contains(ov.Rejected[0], rule) {
+				t.Errorf("rejected = %v, want one entry for %s", ov.Rejected, rule)
+			}
+		})
+	}
+}
+
+func TestApplyRepoOverrideAllowsTightening(t *testing.T) {
+	lenient := Rules{
+		RequirePullRequest:  true,
+		RequiredApprovals:   1,
+		RequireStatusChecks: true,
+		RequiredChecks:      []string{"ci/build", "ci/lint"},
+		AllowForcePushes:    true,
+		AllowDeletions:      true,
+	}
+
+	tests := []struct {
+		name string
+		file string
+		want func(r *Rules)
+	}{
+		{"more approvals", "required_approvals: 3", func(r *Rules) { r.RequiredApprovals = 3 }},
+		{"enforce admins", "enforce_admins: true", func(r *Rules) { r.EnforceAdmins = true }},
+		{"forbid force pushes", "allow_force_pushes: false", func(r *Rules) { r.AllowForcePushes = false }},
+		{"forbid deletions", "allow_deletions: false", func(r *Rules) { r.AllowDeletions = false }},
+		{"extra check", "required_checks: [ci/lint, ci/build, ci/e2e]", func(r *Rules) {
+			r.RequiredChecks = []string{"ci/lint", "ci/build", "ci/e2e"}
+		}},
+		{"same checks reordered", "required_checks: [ci/lint, ci/build]", func(r *Rules) {
+			r.RequiredChecks = []string{"ci/lint", "ci/build"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ov, err := ApplyRepoOverride(lenient, []byte("rules:\n  "+tt.file+"\n"), RuleNames)
+			if err != nil {
+				t.Fatalf("ApplyRepoOverride: %v", err)
+			}
+			want := lenient
+			want.RequiredChecks = append([]string{}, lenient.RequiredChecks...)
+			tt.want(&want)
+			if !reflect.DeepEqual(ov.Rules, want) {
+				t.Errorf("rules = %+v, want %+v", ov.Rules, want)
+			}
+			if len(ov.Rejected) != 0 {
+				t.Errorf("rejected = %v, want none", ov.Rejected)
+			}
+		})
+	}
+}
+
+func TestApplyRepoOverrideRequiredChecksSubset(t *testing.T) {
+	central := strictRules()
+	tests := []struct {
+		name     string
+		checks   string
+		rejected bool
+	}{
+		{"drops one", "[ci/build]", true},
+		{"drops all", "[]", true},
+		{"swaps one", "[ci/build, ci/test]", true},
+		{"keeps all", "[ci/build, ci/lint]", false},
+		{"adds one", "[ci/build, ci/lint, ci/test]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ov, err := ApplyRepoOverride(central, []byte("rules:\n  required_checks: "+tt.checks+"\n"), []string{"required_checks"})
+			if err != nil {
+				t.Fatalf("ApplyRepoOverride: %v", err)
+			}
+			if got := len(ov.Rejected) > 0; got != tt.rejected {
+				t.Errorf("rejected = %v, want rejected %v", ov.Rejected, tt.rejected)
+			}
+			if tt.rejected && !reflect.DeepEqual(ov.Rules.RequiredChecks, central.RequiredChecks) {
+				t.Errorf("required_checks = %v, want the central %v", ov.Rules.RequiredChecks, central.RequiredChecks)
+			}
+		})
+	}
+}
+
+func TestApplyRepoOverrideWithoutTightenOnly(t *testing.T) {
+	central := strictRules()
+	ov, err := ApplyRepoOverride(central, []byte("rules:\n  required_approvals: 1\n  allow_force_pushes: true\n"), nil)
+	if err != nil {
+		t.Fatalf("ApplyRepoOverride: %v", err)
+	}
+	if ov.Rules.RequiredApprovals != 1 || !ov.Rules.AllowForcePushes {
+		t.Errorf("rules = %+v, want the repo's looser values", ov.Rules)
+	}
+	if ov.Sources["required_approvals"] != SourceRepo || ov.Sources["enforce_admins"] != SourceCentral {
+		t.Errorf("sources = %v", ov.Sources)
+	}
+	if len(ov.Rejected) != 0 {
+		t.Errorf("rejected = %v, want none", ov.Rejected)
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is a named set of rules for the repos it matches. Its rules are
+// merged over the base rules, so it only lists the rules that differ.
+type Policy struct {
+	Name  string      `yaml:"name"`
+	Match PolicyMatch `yaml:"match"`
+	// Rules is kept as YAML so that only the rules it sets are applied
+	Rules yaml.Node `yaml:"rules,omitempty"`
+}
+
+// PolicyMatch selects repos by their GitHub custom property values
+type PolicyMatch struct {
+	Properties map[string]PropertyValues `yaml:"properties"`
+}
+
+// PropertyValues lists the accepted values of a custom property. In YAML it
+// can be a single value or a list.
+type PropertyValues []string
+
+// UnmarshalYAML accepts a scalar or a sequence of scalars
+func (v *PropertyValues) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*v = PropertyValues{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+// MatchProperties reports whether a repo's custom properties satisfy every
+// selector. A property matches when any of the repo's values for it (more
+// than one for multi-select properties) is accepted. No selectors match
+// every repo.
+func MatchProperties(selectors map[string]PropertyValues, props map[string][]string) bool {
+	for name, accepted := range selectors {
+		found := false
+		for _, have := range props[name] {
+			for _, want := range accepted {
+				if strings.EqualFold(have, want) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// UsesProperties reports whether any selector or policy needs repos' custom
+// property values
+func (c Config) UsesProperties() bool {
+	return len(c.Select.Properties) > 0 || len(c.Policies) > 0
+}
+
+// PolicyFor returns the first policy matching a repo's custom properties,
+// or nil if the base rules apply
+func (c Config) PolicyFor(props map[string][]string) *Policy {
+	for i := range c.Policies {
+		if MatchProperties(c.Policies[i].Match.Properties, props) {
+			return &c.Policies[i]
+		}
+	}
+	return nil
+}
+
+// PolicyRules returns the base rules with the policy's rules merged over them
+func (c Config) PolicyRules(p Policy) (Rules, error) {
+	rules := c.Rules
+	rules.RequiredChecks = append([]string{}, c.Rules.RequiredChecks...)
+	if p.Rules.Kind == 0 || p.Rules.Tag == "!!null" {
+		return rules, nil
+	}
+	if p.Rules.Kind != yaml.MappingNode {
+		return Rules{}, fmt.Errorf("policy %s: rules must be a mapping", p.Name)
+	}
+
+	known := make(map[string]bool, len(RuleNames))
+	for _, name := range RuleNames {
+		known[name] = true
+	}
+	for i := 0; i < len(p.Rules.Content); i += 2 {
+		if key := p.Rules.Content[i].Value; !known[key] {
+			return Rules{}, fmt.Errorf("policy %s: unknown rule %q", p.Name, key)
+		}
+	}
+
+	if err := p.Rules.Decode(&rules); err != nil {
+		return Rules{}, fmt.Errorf("policy %s: %w", p.Name, err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPolicyWithoutRulesRoundTrips(t *testing.T) {
+	for name, src := range map[string]string{
+		"omitted": "name: critical\nmatch:\n  properties:\n    tier: critical\n",
+		"null":    "name: critical\nmatch:\n  properties:\n    tier: critical\nrules:\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			var p Policy
+			if err := yaml.Unmarshal([]byte(src), &p); err != nil {
+				t.Fatal(err)
+			}
+			out, err := yaml.Marshal(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var again Policy
+			if err := yaml.Unmarshal(out, &again); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := Default()
+			rules, err := cfg.PolicyRules(again)
+			if err != nil {
+				t.Fatalf("PolicyRules after round trip of\n%s: %v", out, err)
+			}
+			if rules.RequiredApprovals != cfg.Rules.RequiredApprovals {
+				t.Errorf("required_approvals = %d, want the base %d", rules.RequiredApprovals, cfg.Rules.RequiredApprovals)
+			}
+		})
+	}
+}
+
+func TestValidateLocatesPolicyProblems(t *testing.T) {
+	const head = `rules:
+  require_pull_request: true
+policies:
+  - name: web
+    match:
+      properties:
+        tier: web
+  - name: strict
+    match:
+      properties:
+        tier: critical
+    rules:
+`
+	tests := []struct {
+		name  string
+		rules string
+		want  string
+	}{
+		{"unknown rule", "      required_approvals: 2\n      require_signatures: true\n",
+			`line 14: policies[1]: policy strict: unknown rule "require_signatures"`},
+		{"invalid rule", "      required_approvals: 2\n      required_checks: [\"\"]\n",
+			"line 14: policies[1]: required_checks is set but require_status_checks is false"},
+		{"not a mapping", "      - required_approvals\n",
+			"line 12: policies[1]: policy strict: rules must be a mapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadString(t, head+tt.rules)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	_, err := loadString(t, head+"      required_approvals: 2\n  - match:\n      properties:\n        tier: api\n")
+	if want := "line 14: policies[2]: name is required"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %v, want it to contain %q", err, want)
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import "math"
+
+// Weight returns the configured score weight of a rule (1 by default)
+func (c Config) Weight(rule string) float64 {
+	if w, ok := c.Weights[rule]; ok {
+		return w
+	}
+	return 1
+}
+
+// Score returns a 0–100 compliance score for a repo: the weighted share of
+// compared rules that pass. Exempt rules don't count either way, and a repo
+// with nothing to compare scores 100.
+func (c Config) Score(diffs []RuleDiff) float64 {
+	var total, passing float64
+	for _, d := range diffs {
+		if d.Exempt {
+			continue
+		}
+		w := c.Weight(d.Rule)
+		total += w
+		if d.Pass {
+			passing += w
+		}
+	}
+	if total == 0 {
+		return 100
+	}
+	return math.Round(passing/total*1000) / 10
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selectionDateLayout is the format of Selection.CreatedAfter
+const selectionDateLayout = "2006-01-02"
+
+// Selection controls which of an owner's repos are audited
+type Selection struct {
+	// IncludeForks audits forks, which are left out by default
+	IncludeForks bool `yaml:"include_forks,omitempty"`
+	// IncludeArchived lists archived repos. They're read-only, so they're
+	// reported as archived rather than checked.
+	IncludeArchived bool `yaml:"include_archived,omitempty"`
+	// Properties only audits repos whose custom properties match
+	Properties map[string]PropertyValues `yaml:"properties,omitempty"`
+	// PushedWithin, e.g. "365d" or "12w", reports repos without a push in
+	// that window as inactive rather than checking them
+	PushedWithin string `yaml:"pushed_within,omitempty"`
+	// CreatedAfter (YYYY-MM-DD) reports repos created before that day as
+	// inactive
+	CreatedAfter string `yaml:"created_after,omitempty"`
+}
+
+// PushedWindow parses PushedWithin; zero means no limit
+func (s Selection) PushedWindow() (time.Duration, error) {
+	if s.PushedWithin == "" {
+		return 0, nil
+	}
+	v := strings.TrimSpace(s.PushedWithin)
+	unitDays := map[string]int{"d": 1, "w": 7}
+	if len(v) > 1 {
+		if days, ok := unitDays[v[len(v)-1:]]; ok {
+			if n, err := strconv.Atoi(v[:len(v)-1]); err == nil && n > 0 {
+				return time.Duration(n*days) * 24 * time.Hour, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("select.pushed_within must be a number of days or weeks like 365d or 52w, got %q", s.PushedWithin)
+}
+
+// CreatedAfterDate parses CreatedAfter; the zero time means no limit
+func (s Selection) CreatedAfterDate() (time.Time, error) {
+	if s.CreatedAfter == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(selectionDateLayout, s.CreatedAfter)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("select.created_after must be a date like 2024-01-31, got %q", s.CreatedAfter)
+	}
+	return t, nil
+}
+
+// Inactive explains why a repo falls outside the activity window, or returns
+// "" when it's active. Unknown (zero) times never make a repo inactive.
+func (s Selection) Inactive(pushedAt, createdAt, now time.Time) string {
+	if after, err := s.CreatedAfterDate(); err == nil && !after.IsZero() && !createdAt.IsZero() && createdAt.Before(after) {
+		return fmt.Sprintf("created %s, before %s", createdAt.UTC().Format(selectionDateLayout), s.CreatedAfter)
+	}
+	if window, err := s.PushedWindow(); err == nil && window > 0 && !pushedAt.IsZero() && now.Sub(pushedAt) > window {
+		return fmt.Sprintf("last push %s, over %s ago", pushedAt.UTC().Format(selectionDateLayout), s.PushedWithin)
+	}
+	return ""
+}
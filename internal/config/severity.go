@@ -0,0 +1,54 @@
+package config
+
+// Rule severities, lowest to highest
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severityRank = map[string]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// DefaultSeverities is the severity of each rule unless the config overrides it
+var DefaultSeverities = map[string]string{
+	"require_pull_request":             SeverityCritical,
+	"required_approvals":               SeverityHigh,
+	"dismiss_stale_reviews":            SeverityMedium,
+	"require_code_owner_reviews":       SeverityMedium,
+	"require_status_checks":            SeverityHigh,
+	"strict_status_checks":             SeverityLow,
+	"required_checks":                  SeverityHigh,
+	"enforce_admins":                   SeverityHigh,
+	"allow_force_pushes":               SeverityCritical,
+	"allow_deletions":                  SeverityCritical,
+	"required_linear_history":          SeverityLow,
+	"required_conversation_resolution": SeverityLow,
+}
+
+// ValidSeverity reports whether s is a known severity
+func ValidSeverity(s string) bool {
+	_, ok := severityRank[s]
+	return ok
+}
+
+// SeverityAtLeast reports whether severity is at or above threshold
+func SeverityAtLeast(severity, threshold string) bool {
+	return severityRank[severity] >= severityRank[threshold]
+}
+
+// Severity returns the configured severity of a rule
+func (c Config) Severity(rule string) string {
+	if s, ok := c.Severities[rule]; ok {
+		return s
+	}
+	if s, ok := DefaultSeverities[rule]; ok {
+		return s
+	}
+	return SeverityMedium
+}
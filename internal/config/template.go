@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPattern matches ${VAR} and ${VAR:-default}. A leading $$ escapes the
+// reference so it is kept literally (minus one $).
+var envPattern = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// expandEnv substitutes environment variable references in the scalar
+// values of a parsed config. Keys and comments are left alone, and values
+// can't inject YAML since they're never parsed. Referencing an unset variable
+// without a default is an error, since an empty value would usually produce
+// a config that silently checks nothing.
+func expandEnv(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value, err := expandString(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		if value != node.Value {
+			node.Value = value
+			if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				// Let an unquoted value resolve as written, e.g. ${N} as an int
+				node.Tag = ""
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := expandEnv(node.Content[i]); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := expandEnv(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expandString substitutes the environment variable references in s
+func expandString(s string) (string, error) {
+	var out strings.Builder
+	last := 0
+	for _, m := range envPattern.FindAllStringSubmatchIndex(s, -1) {
+		out.WriteString(s[last:m[0]])
+		last = m[1]
+
+		if m[3] > m[2] {
+			// $${VAR}: drop the escape and keep the reference as written
+			out.WriteString(s[m[0]+1 : m[1]])
+			continue
+		}
+
+		name := s[m[4]:m[5]]
+		value, ok := os.LookupEnv(name)
+		if !ok || (value == "" && m[6] >= 0) {
+			if m[6] < 0 {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			value = s[m[6]+2 : m[7]]
+		}
+		out.WriteString(value)
+	}
+	out.WriteString(s[last:])
+	return out.String(), nil
+}
+
+// RepoVars are the values available to per-repo templates in rules
+type RepoVars struct {
+	Owner  string
+	Repo   string
+	Branch string
+}
+
+// ForRepo renders templated rule values (currently required_checks, e.g.
+// "ci/{{.Repo}}") for a specific repo
+func (r Rules) ForRepo(vars RepoVars) (Rules, error) {
+	rendered := r
+	rendered.RequiredChecks = make([]string, len(r.RequiredChecks))
+	for i, check := range r.RequiredChecks {
+		value, err := renderTemplate(check, vars)
+		if err != nil {
+			return Rules{}, fmt.Errorf("required_checks %q: %w", check, err)
+		}
+		rendered.RequiredChecks[i] = value
+	}
+	return rendered, nil
+}
+
+func renderTemplate(text string, vars RepoVars) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadString(t *testing.T, src string) (Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rampart.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoadExpandsEnvInValues(t *testing.T) {
+	t.Setenv("RAMPART_APPROVALS", "2")
+	t.Setenv("RAMPART_CHECK", "ci/build")
+
+	cfg, err := loadString(t, `# uses ${RAMPART_UNSET} in a comment
+branch: ${RAMPART_BRANCH:-main}
+rules:
+  require_pull_request: true
+  required_approvals: ${RAMPART_APPROVALS}
+  require_status_checks: true
+  required_checks: ["${RAMPART_CHECK}", "$${KEPT}"]
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Branch != "main" {
+		t.Errorf("branch = %q, want the default main", cfg.Branch)
+	}
+	if cfg.Rules.RequiredApprovals != 2 {
+		t.Errorf("required_approvals = %d, want 2", cfg.Rules.RequiredApprovals)
+	}
+	if got := strings.Join(cfg.Rules.RequiredChecks, ","); got != "ci/build,${KEPT}" {
+		t.Errorf("required_checks = %s, want ci/build,${KEPT}", got)
+	}
+}
+
+func TestLoadEnvValuesCantInjectKeys(t *testing.T) {
+	t.Setenv("RAMPART_BRANCH", "main\nrules:\n  enforce_admins: false")
+
+	cfg, err := loadString(t, "branch: ${RAMPART_BRANCH}\nrules:\n  enforce_admins: true\n")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.Rules.EnforceAdmins {
+		t.Error("enforce_admins was overridden by an environment variable's value")
+	}
+	if cfg.Branch != "main\nrules:\n  enforce_admins: false" {
+		t.Errorf("branch = %q, want the variable's value as is", cfg.Branch)
+	}
+}
+
+func TestLoadEnvErrors(t *testing.T) {
+	tests := map[string]struct {
+		src  string
+		want string
+	}{
+		"unset variable": {
+			src:  "branch: main\nrules:\n  required_checks: [\"${RAMPART_UNSET}\"]\n",
+			want: "line 3: environment variable RAMPART_UNSET is not set",
+		},
+		"unknown key": {
+			src:  "branch: ${RAMPART_BRANCH:-main}\nrules:\n  enforce_admin: true\n",
+			want: "line 3: field enforce_admin not found",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadString(t, tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxRequiredApprovals is the most approving reviews GitHub allows
+const MaxRequiredApprovals = 6
+
+// Validate checks constraints the YAML structure can't express
+func (c Config) Validate() error {
+	return c.validate(nil)
+}
+
+// validate checks c and, when the source files are available, prefixes each
+// problem with the location of the offending key. With an extends chain the
+// location is the file that last set the key.
+func (c Config) validate(chain []source) error {
+	var problems []string
+	add := func(key, format string, args ...interface{}) {
+		problems = append(problems, prefixLocation(locate(chain, "rules", key), fmt.Sprintf(format, args...)))
+	}
+
+	checkRules(c.Rules, add)
+
+	known := make(map[string]bool, len(RuleNames))
+	for _, name := range RuleNames {
+		known[name] = true
+	}
+	for _, rule := range c.RepoConfig.TightenOnly {
+		if !known[rule] {
+			problems = append(problems, prefixLocation(locate(chain, "repo_config", "tighten_only"),
+				fmt.Sprintf("repo_config.tighten_only: unknown rule %q", rule)))
+		}
+	}
+
+	for _, rule := range sortedKeys(c.Severities) {
+		loc := locate(chain, "severities", rule)
+		if !known[rule] {
+			problems = append(problems, prefixLocation(loc, fmt.Sprintf("severities: unknown rule %q", rule)))
+		} else if !ValidSeverity(c.Severities[rule]) {
+			problems = append(problems, prefixLocation(loc, fmt.Sprintf(
+				"severities.%s must be low, medium, high or critical, got %q", rule, c.Severities[rule])))
+		}
+	}
+
+	for _, rule := range sortedKeys(c.Weights) {
+		w := c.Weights[rule]
+		loc := locate(chain, "weights", rule)
+		if !known[rule] {
+			problems = append(problems, prefixLocation(loc, fmt.Sprintf("weights: unknown rule %q", rule)))
+		} else if w < 0 {
+			problems = append(problems, prefixLocation(loc, fmt.Sprintf("weights.%s must not be negative, got %g", rule, w)))
+		}
+	}
+
+	seenPolicies := make(map[string]bool, len(c.Policies))
+	for i, p := range c.Policies {
+		itemLoc, within := locatePolicy(chain, i)
+		label := fmt.Sprintf("policies[%d]", i)
+		where := prefixLocation(itemLoc, label)
+		switch {
+		case p.Name == "":
+			problems = append(problems, where+": name is required")
+		case seenPolicies[p.Name]:
+			problems = append(problems, fmt.Sprintf("%s: policy %q is defined more than once", where, p.Name))
+		}
+		seenPolicies[p.Name] = true
+		if len(p.Match.Properties) == 0 {
+			problems = append(problems, prefixLocation(orLocation(locate(within, "match"), itemLoc), label+": match.properties is required"))
+		}
+		rules, err := c.PolicyRules(p)
+		if err != nil {
+			loc := locate(within, "rules")
+			for j := 0; j+1 < len(p.Rules.Content) && p.Rules.Kind == yaml.MappingNode; j += 2 {
+				if key := p.Rules.Content[j].Value; !known[key] {
+					loc = locate(within, "rules", key)
+					break
+				}
+			}
+			problems = append(problems, fmt.Sprintf("%s: %s", prefixLocation(orLocation(loc, itemLoc), label), err))
+			continue
+		}
+		checkRules(rules, func(key, format string, args ...interface{}) {
+			loc := orLocation(locate(within, "rules", key), itemLoc)
+			problems = append(problems, prefixLocation(loc, label+": "+fmt.Sprintf(format, args...)))
+		})
+	}
+
+	seenOwners := make(map[string]bool, len(c.Owners))
+	for _, owner := range c.Owners {
+		loc := locate(chain, "owners")
+		switch {
+		case strings.TrimSpace(owner) == "":
+			problems = append(problems, prefixLocation(loc, "owners must not contain empty names"))
+		case seenOwners[strings.ToLower(owner)]:
+			problems = append(problems, prefixLocation(loc, fmt.Sprintf("owners: %q is listed more than once", owner)))
+		}
+		seenOwners[strings.ToLower(owner)] = true
+	}
+
+	if _, err := c.Select.PushedWindow(); err != nil {
+		problems = append(problems, prefixLocation(locate(chain, "select", "pushed_within"), err.Error()))
+	}
+	if _, err := c.Select.CreatedAfterDate(); err != nil {
+		problems = append(problems, prefixLocation(locate(chain, "select", "created_after"), err.Error()))
+	}
+
+	exemptionsLoc := locate(chain, "exemptions")
+	for i, e := range c.Exemptions {
+		where := prefixLocation(exemptionsLoc, fmt.Sprintf("exemptions[%d]", i))
+		if e.Repo == "" {
+			problems = append(problems, where+": repo is required")
+		}
+		if e.Rule != "" && !known[e.Rule] {
+			problems = append(problems, fmt.Sprintf("%s: unknown rule %q", where, e.Rule))
+		}
+		if e.Reason == "" {
+			problems = append(problems, where+": reason is required")
+		}
+		if e.Approver == "" {
+			problems = append(problems, where+": approver is required")
+		}
+		if _, err := e.ExpiresOn(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: expires must be a date like 2026-12-31, got %q", where, e.Expires))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// checkRules reports problems with a set of rules through add, keyed by the
+// rule at fault
+func checkRules(r Rules, add func(key, format string, args ...interface{})) {
+	if r.RequiredApprovals < 0 || r.RequiredApprovals > MaxRequiredApprovals {
+		add("required_approvals", "required_approvals must be between 0 and %d, got %d",
+			MaxRequiredApprovals, r.RequiredApprovals)
+	}
+	if len(r.RequiredChecks) > 0 && !r.RequireStatusChecks {
+		add("required_checks", "required_checks is set but require_status_checks is false")
+	}
+	for _, check := range r.RequiredChecks {
+		if strings.TrimSpace(check) == "" {
+			add("required_checks", "required_checks must not contain empty names")
+			break
+		}
+	}
+	if _, err := r.ForRepo(RepoVars{Owner: "owner", Repo: "repo", Branch: "main"}); err != nil {
+		add("required_checks", "invalid template in %s", err)
+	}
+	if r.RequireCodeOwnerReviews && !r.RequirePullRequest {
+		add("require_code_owner_reviews", "require_code_owner_reviews requires require_pull_request")
+	}
+}
+
+// locatePolicy describes where policies[i] is defined and returns a chain
+// holding just that entry, so locate finds keys within it. Sequences aren't
+// merged, so the entry comes from the last file to list policies.
+func locatePolicy(chain []source, i int) (string, []source) {
+	within := make([]source, len(chain))
+	for j := range chain {
+		within[j].name = chain[j].name
+	}
+	for j := len(chain) - 1; j >= 0; j-- {
+		_, policies := lookupKey(chain[j].doc, "policies")
+		if policies == nil {
+			continue
+		}
+		if policies.Kind != yaml.SequenceNode || i >= len(policies.Content) {
+			break
+		}
+		within[j].doc = policies.Content[i]
+		return sourceLine(chain, j, policies.Content[i].Line), within
+	}
+	return locate(chain, "policies"), within
+}
+
+// orLocation returns loc, or fallback when loc is unknown
+func orLocation(loc, fallback string) string {
+	if loc == "" {
+		return fallback
+	}
+	return loc
+}
+
+// locate describes where the key at path was last set in an extends chain
+func locate(chain []source, path ...string) string {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if line := keyLine(chain[i].doc, path...); line > 0 {
+			return sourceLine(chain, i, line)
+		}
+	}
+	return ""
+}
+
+// sourceLine describes a line in chain[i], naming the file when the chain
+// has more than one
+func sourceLine(chain []source, i, line int) string {
+	if len(chain) == 1 {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("%s: line %d", chain[i].name, line)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func prefixLocation(loc, msg string) string {
+	if loc == "" {
+		return msg
+	}
+	return loc + ": " + msg
+}
+
+// keyLine returns the line of the key at path in a YAML document, or 0 if
+// the document is nil or the key isn't present
+func keyLine(doc *yaml.Node, path ...string) int {
+	if key, _ := lookupKey(doc, path...); key != nil {
+		return key.Line
+	}
+	return 0
+}
+
+// lookupKey returns the key and value nodes at path in a YAML document or
+// mapping, or nils if the document is nil or the key isn't present
+func lookupKey(doc *yaml.Node, path ...string) (key, value *yaml.Node) {
+	if doc == nil {
+		return nil, nil
+	}
+	node := doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, name := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil, nil
+		}
+		key = nil
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == name {
+				key, node = node.Content[i], node.Content[i+1]
+				break
+			}
+		}
+		if key == nil {
+			return nil, nil
+		}
+	}
+	return key, node
+}
@@ -0,0 +1,43 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// OrgMembership is the authenticated user's membership in an organization
+type OrgMembership struct {
+	// State is "active" or "pending"
+	State string `json:"state"`
+	// Role is "admin" or "member"
+	Role         string `json:"role"`
+	Organization struct {
+		Login string `json:"login"`
+	} `json:"organization"`
+}
+
+// ListOrgMemberships lists the organizations the authenticated user belongs
+// to or has been invited to
+func ListOrgMemberships() ([]OrgMembership, error) {
+	cmd := exec.Command("gh", "api", "user/memberships/orgs?per_page=100", "--paginate")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "read:org") || strings.Contains(stderr, "403") {
+				return nil, fmt.Errorf("listing organizations needs the read:org scope\n\nRun: gh auth refresh -s read:org")
+			}
+			return nil, fmt.Errorf("gh api failed: %s", stderr)
+		}
+		return nil, fmt.Errorf("failed to run gh: %w", err)
+	}
+
+	var memberships []OrgMembership
+	if err := json.Unmarshal(output, &memberships); err != nil {
+		return nil, fmt.Errorf("failed to parse org memberships: %w", err)
+	}
+
+	return memberships, nil
+}
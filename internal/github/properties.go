@@ -0,0 +1,61 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// repoPropertyValues is one entry of the org custom property values API
+type repoPropertyValues struct {
+	RepositoryName string `json:"repository_name"`
+	Properties     []struct {
+		Name string `json:"property_name"`
+		// Value is a string, a list of strings for multi-select
+		// properties, or null when unset
+		Value json.RawMessage `json:"value"`
+	} `json:"properties"`
+}
+
+// GetRepoProperties returns the custom property values of every repo in an
+// org, keyed by repo name and then property name. Users can't define custom
+// properties, so a user owner has none.
+func GetRepoProperties(owner string) (map[string]map[string][]string, error) {
+	endpoint := fmt.Sprintf("orgs/%s/properties/values?per_page=100", owner)
+	cmd := exec.Command("gh", "api", endpoint, "--paginate")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "404") || strings.Contains(stderr, "Not Found") {
+				return map[string]map[string][]string{}, nil
+			}
+			return nil, fmt.Errorf("gh api failed: %s", strings.TrimSpace(stderr))
+		}
+		return nil, fmt.Errorf("failed to run gh: %w", err)
+	}
+
+	var entries []repoPropertyValues
+	if err := json.Unmarshal(output, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse custom properties: %w", err)
+	}
+
+	props := make(map[string]map[string][]string, len(entries))
+	for _, e := range entries {
+		values := make(map[string][]string, len(e.Properties))
+		for _, p := range e.Properties {
+			var one string
+			var many []string
+			switch {
+			case string(p.Value) == "null":
+			case json.Unmarshal(p.Value, &one) == nil:
+				values[p.Name] = []string{one}
+			case json.Unmarshal(p.Value, &many) == nil && many != nil:
+				values[p.Name] = many
+			}
+		}
+		props[e.RepositoryName] = values
+	}
+	return props, nil
+}
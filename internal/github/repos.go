@@ -2,19 +2,32 @@ package github
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/wdm0006/rampart/internal/config"
 )
 
+// ErrNotFound is returned when a requested file or resource doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// ErrBranchNotFound is returned when a repo has no branch by the requested
+// name, including every branch of an empty repo
+var ErrBranchNotFound = errors.New("branch not found")
+
 type Repo struct {
 	Name          string `json:"name"`
 	Fork          bool   `json:"fork"`
 	Archived      bool   `json:"archived"`
 	DefaultBranch string `json:"default_branch"`
+	// PushedAt and CreatedAt are zero when GitHub doesn't report them
+	PushedAt  time.Time `json:"pushed_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // GetCurrentUser returns the currently authenticated GitHub username
@@ -38,35 +51,96 @@ func GetCurrentUser() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// ListRepos lists non-fork, non-archived repos for an owner (user or org)
-func ListRepos(owner string) ([]Repo, error) {
-	// Try user repos first
-	repos, err := listReposFromEndpoint(fmt.Sprintf("users/%s/repos?type=owner&per_page=100", owner))
+// Owner types reported by the users API
+const (
+	OwnerUser         = "User"
+	OwnerOrganization = "Organization"
+)
+
+// GetOwnerType reports whether owner is a user or an organization
+func GetOwnerType(owner string) (string, error) {
+	cmd := exec.Command("gh", "api", fmt.Sprintf("users/%s", owner), "--jq", ".type")
+	output, err := cmd.Output()
 	if err != nil {
-		// Fall back to org repos
-		repos, err = listReposFromEndpoint(fmt.Sprintf("orgs/%s/repos?per_page=100", owner))
-		if err != nil {
-			return nil, fmt.Errorf("failed to list repos for %s: %w", owner, err)
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "404") || strings.Contains(stderr, "Not Found") {
+				return "", fmt.Errorf("no GitHub user or organization named %s", owner)
+			}
+			return "", fmt.Errorf("gh api failed: %s", stderr)
 		}
+		return "", fmt.Errorf("failed to run gh: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ListOptions controls which repos ListRepos returns
+type ListOptions struct {
+	IncludeForks    bool
+	IncludeArchived bool
+}
+
+// ListRepos lists repos for an owner (user or org), including private and
+// internal repos the authenticated user can see. Forks and archived repos
+// are left out unless opts asks for them.
+func ListRepos(owner string, opts ListOptions) ([]Repo, error) {
+	endpoint, err := reposEndpoint(owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repos for %s: %w", owner, err)
+	}
+	repos, err := listReposFromEndpoint(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repos for %s: %w", owner, err)
 	}
 
-	// Filter out forks and archived repos
 	var filtered []Repo
 	for _, r := range repos {
-		if !r.Fork && !r.Archived {
-			filtered = append(filtered, r)
+		if (r.Fork && !opts.IncludeForks) || (r.Archived && !opts.IncludeArchived) {
+			continue
 		}
+		filtered = append(filtered, r)
 	}
 
 	return filtered, nil
 }
 
+// reposEndpoint picks the endpoint that lists all of owner's repos. The
+// users endpoint only returns public repos, so orgs are listed through the
+// orgs endpoint and the authenticated user through their own repo list.
+func reposEndpoint(owner string) (string, error) {
+	ownerType, err := GetOwnerType(owner)
+	if err != nil {
+		return "", err
+	}
+	switch ownerType {
+	case OwnerOrganization:
+		return fmt.Sprintf("orgs/%s/repos?type=all&per_page=100", owner), nil
+	case OwnerUser:
+	default:
+		return "", fmt.Errorf("%s is a %s, not a user or organization", owner, ownerType)
+	}
+
+	user, err := GetCurrentUser()
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(user, owner) {
+		return "user/repos?affiliation=owner&per_page=100", nil
+	}
+	return fmt.Sprintf("users/%s/repos?type=owner&per_page=100", owner), nil
+}
+
 func listReposFromEndpoint(endpoint string) ([]Repo, error) {
 	cmd := exec.Command("gh", "api", endpoint, "--paginate")
 	output, err := cmd.Output()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("gh api failed: %s", string(exitErr.Stderr))
+			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "SAML") {
+				return nil, fmt.Errorf("the organization requires SAML single sign-on authorization for your token")
+			}
+			return nil, fmt.Errorf("gh api failed: %s", stderr)
 		}
 		return nil, fmt.Errorf("failed to run gh: %w", err)
 	}
@@ -85,7 +159,11 @@ func GetRepo(owner, name string) (Repo, error) {
 	output, err := cmd.Output()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return Repo{}, fmt.Errorf("gh api failed: %s", string(exitErr.Stderr))
+			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "404") || strings.Contains(stderr, "Not Found") {
+				return Repo{}, fmt.Errorf("repo %s/%s not found or not accessible", owner, name)
+			}
+			return Repo{}, fmt.Errorf("gh api failed: %s", strings.TrimSpace(stderr))
 		}
 		return Repo{}, fmt.Errorf("failed to run gh: %w", err)
 	}
@@ -98,8 +176,32 @@ func GetRepo(owner, name string) (Repo, error) {
 	return repo, nil
 }
 
+// GetFileContents fetches the raw contents of a file in a repo. An empty ref
+// reads from the default branch.
+func GetFileContents(owner, repo, path, ref string) ([]byte, error) {
+	endpoint := fmt.Sprintf("repos/%s/%s/contents/%s", owner, repo, path)
+	if ref != "" {
+		endpoint += "?ref=" + url.QueryEscape(ref)
+	}
+	cmd := exec.Command("gh", "api", endpoint, "-H", "Accept: application/vnd.github.raw+json")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "404") || strings.Contains(stderr, "Not Found") {
+				return nil, ErrNotFound
+			}
+			return nil, fmt.Errorf("gh api failed: %s", stderr)
+		}
+		return nil, fmt.Errorf("failed to run gh: %w", err)
+	}
+
+	return output, nil
+}
+
 // GetBranchProtection gets the current branch protection rules for a repo.
-// Returns zero Rules if no protection is set (404).
+// Returns zero Rules if no protection is set (404), and ErrBranchNotFound if
+// the branch doesn't exist.
 // Returns an error string for permission errors (403) that should be surfaced per-repo.
 func GetBranchProtection(owner, repo, branch string) (config.Rules, bool, error) {
 	endpoint := fmt.Sprintf("repos/%s/%s/branches/%s/protection", owner, repo, branch)
@@ -108,6 +210,9 @@ func GetBranchProtection(owner, repo, branch string) (config.Rules, bool, error)
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			stderr := string(exitErr.Stderr)
+			if strings.Contains(stderr, "Branch not found") {
+				return config.Rules{}, false, ErrBranchNotFound
+			}
 			// 404 = no protection configured
 			if strings.Contains(stderr, "404") || strings.Contains(stderr, "Not Found") ||
 				strings.Contains(stderr, "Branch not protected") {
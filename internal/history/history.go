@@ -0,0 +1,134 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Repo statuses, matching the audit statuses in the cli package
+const (
+	StatusCompliant    = "compliant"
+	StatusNonCompliant = "non_compliant"
+)
+
+// Run is a single audit recorded in the history file
+type Run struct {
+	Time         time.Time `json:"time"`
+	Owner        string    `json:"owner"`
+	Config       string    `json:"config"`
+	Compliant    int       `json:"compliant"`
+	NonCompliant int       `json:"non_compliant"`
+	Errors       int       `json:"errors"`
+	Skipped      int       `json:"skipped"`
+	Total        int       `json:"total"`
+	// Repos is keyed by owner/name
+	Repos map[string]Repo `json:"repos"`
+}
+
+// Repo is the recorded outcome for one repo in a run
+type Repo struct {
+	Status  string   `json:"status"`
+	Failing []string `json:"failing,omitempty"`
+}
+
+// Percent returns the share of audited repos (compliant, non-compliant or
+// errored) that were compliant
+func (r Run) Percent() int {
+	audited := r.Compliant + r.NonCompliant + r.Errors
+	if audited == 0 {
+		return 0
+	}
+	return r.Compliant * 100 / audited
+}
+
+// Changes lists how repos moved between two runs
+type Changes struct {
+	NewlyNonCompliant []string
+	Fixed             []string
+}
+
+// Compare returns the repos that became non-compliant and the repos that
+// were fixed between prev and cur
+func Compare(prev, cur Run) Changes {
+	var c Changes
+	for name, repo := range cur.Repos {
+		before, seen := prev.Repos[name]
+		switch {
+		case repo.Status == StatusNonCompliant && (!seen || before.Status != StatusNonCompliant):
+			c.NewlyNonCompliant = append(c.NewlyNonCompliant, name)
+		case repo.Status == StatusCompliant && seen && before.Status == StatusNonCompliant:
+			c.Fixed = append(c.Fixed, name)
+		}
+	}
+	sort.Strings(c.NewlyNonCompliant)
+	sort.Strings(c.Fixed)
+	return c
+}
+
+// Load reads all runs from a history file. A missing file has no runs.
+func Load(path string) ([]Run, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("failed to parse history line %d: %w", line, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	return runs, nil
+}
+
+// Append adds a run to the end of a history file, creating it if needed
+func Append(path string, run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// ForOwner returns the runs recorded for an owner, oldest first
+func ForOwner(runs []Run, owner string) []Run {
+	var out []Run
+	for _, r := range runs {
+		if r.Owner == owner {
+			out = append(out, r)
+		}
+	}
+	return out
+}